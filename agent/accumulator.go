@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -139,3 +140,51 @@ func (ac accumulator) getTime(t []time.Time) time.Time {
 	}
 	return timestamp.Round(ac.precision)
 }
+
+func (ac *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		Accumulator: ac,
+		acc:         ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+type trackingAccumulator struct {
+	telegraf.Accumulator
+	acc       *accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+// AddTrackingMetricGroup adds a group of metrics to the accumulator. The
+// metrics are passed through the plugin's MetricMaker so that name overrides
+// and tags are applied as they are for untracked metrics.
+func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	ac := a.acc
+	made := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		t := []time.Time{m.Time()}
+		if m := ac.maker.MakeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(), ac.getTime(t)); m != nil {
+			made = append(made, m)
+		}
+	}
+
+	tracked, id := metric.WithGroupTracking(made, a.onDelivery)
+	for _, m := range tracked {
+		ac.metrics <- m
+	}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	select {
+	case a.delivered <- info:
+	default:
+		// This is a programming error in the input.  More items were sent for
+		// tracking than space requested.
+		panic("channel is full")
+	}
+}
@@ -309,6 +309,50 @@ func TestAddCounter(t *testing.T) {
 	assert.Equal(t, testm.Type(), telegraf.Counter)
 }
 
+func TestAddTrackingMetricGroup(t *testing.T) {
+	now := time.Now()
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics).WithTracking(1)
+
+	m1, err := metric.New("acctest",
+		map[string]string{"acc": "test"},
+		map[string]interface{}{"value": float64(101)},
+		now)
+	require.NoError(t, err)
+	m2, err := metric.New("acctest",
+		map[string]string{"acc": "test"},
+		map[string]interface{}{"value": float64(102)},
+		now)
+	require.NoError(t, err)
+	id := a.AddTrackingMetricGroup([]telegraf.Metric{m1, m2})
+
+	first := <-metrics
+	second := <-metrics
+	assert.Equal(t,
+		fmt.Sprintf("acctest,acc=test value=101 %d\n", now.UnixNano()),
+		first.String())
+
+	first.Accept()
+	assert.Len(t, a.Delivered(), 0)
+	second.Reject()
+
+	info := <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.False(t, info.Delivered())
+}
+
+func TestAddTrackingEmptyGroup(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics).WithTracking(1)
+
+	id := a.AddTrackingMetricGroup(nil)
+	info := <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+}
+
 type TestMetricMaker struct {
 }
 
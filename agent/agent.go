@@ -18,12 +18,30 @@ import (
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// metricC and aggC are passed on to the next Agent on reload, so that
+	// service inputs and aggregators taken over by it keep working.
+	metricC chan telegraf.Metric
+	aggC    chan telegraf.Metric
+
+	// running holds the plugins taken over from the previous Agent, these
+	// are already started or connected.
+	running map[interface{}]bool
+
+	// handedOver holds the plugins taken over by the next Agent, these are
+	// not stopped or closed when Run returns.
+	mu         sync.Mutex
+	handedOver map[interface{}]bool
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:     config,
+		metricC:    make(chan telegraf.Metric, 100),
+		aggC:       make(chan telegraf.Metric, 100),
+		running:    make(map[interface{}]bool),
+		handedOver: make(map[interface{}]bool),
 	}
 
 	if !a.Config.Agent.OmitHostname {
@@ -42,9 +60,78 @@ func NewAgent(config *config.Config) (*Agent, error) {
 	return a, nil
 }
 
+// Reload returns a new Agent for config c. Plugins that are configured the
+// same way in c as in the configuration of a are taken over by the new Agent
+// without being restarted, keeping their buffered metrics and state. Reload
+// must be called before Run returns.
+func (a *Agent) Reload(c *config.Config) (*Agent, error) {
+	c.Reuse(a.Config)
+
+	next, err := NewAgent(c)
+	if err != nil {
+		return nil, err
+	}
+	next.metricC = a.metricC
+	next.aggC = a.aggC
+
+	current := make(map[interface{}]bool)
+	for _, p := range a.plugins() {
+		current[p] = true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range next.plugins() {
+		if current[p] {
+			next.running[p] = true
+			a.handedOver[p] = true
+		}
+	}
+	return next, nil
+}
+
+// plugins returns all the plugins of the Agent.
+func (a *Agent) plugins() []interface{} {
+	var plugins []interface{}
+	for _, p := range a.Config.Inputs {
+		plugins = append(plugins, p)
+	}
+	for _, p := range a.Config.Outputs {
+		plugins = append(plugins, p)
+	}
+	for _, p := range a.Config.Aggregators {
+		plugins = append(plugins, p)
+	}
+	for _, p := range a.Config.Processors {
+		plugins = append(plugins, p)
+	}
+	return plugins
+}
+
+// isHandedOver returns true if the plugin is taken over by the next Agent.
+func (a *Agent) isHandedOver(plugin interface{}) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.handedOver[plugin]
+}
+
 // Connect connects to all configured outputs
 func (a *Agent) Connect() error {
 	for _, o := range a.Config.Outputs {
+		if a.running[o] {
+			continue
+		}
+
+		// The disk buffer is only opened now, as an output it replaces may
+		// be using the same directory until the previous Agent returns.
+		if o.Config.BufferType == "disk" {
+			if err := o.UseDiskBuffer(o.Config.DiskBuffer); err != nil {
+				log.Printf("E! Unable to open disk buffer for output %s\n%s\n",
+					o.Name, err.Error())
+				return err
+			}
+		}
+
 		switch ot := o.Output.(type) {
 		case telegraf.ServiceOutput:
 			if err := ot.Start(); err != nil {
@@ -74,11 +161,17 @@ func (a *Agent) Connect() error {
 func (a *Agent) Close() error {
 	var err error
 	for _, o := range a.Config.Outputs {
+		if a.isHandedOver(o) {
+			continue
+		}
 		err = o.Output.Close()
 		switch ot := o.Output.(type) {
 		case telegraf.ServiceOutput:
 			ot.Stop()
 		}
+		if berr := o.CloseBuffer(); berr != nil {
+			log.Printf("E! Error closing buffer of output [%s]: %s\n", o.Name, berr)
+		}
 	}
 	return err
 }
@@ -227,27 +320,140 @@ func (a *Agent) Test() error {
 	return nil
 }
 
-// flush writes a list of metrics to all configured outputs
+// flush writes a list of metrics to all configured outputs, except the ones
+// taken over by the next Agent.
 func (a *Agent) flush() {
 	var wg sync.WaitGroup
 
-	wg.Add(len(a.Config.Outputs))
 	for _, o := range a.Config.Outputs {
+		if a.isHandedOver(o) {
+			continue
+		}
+		wg.Add(1)
 		go func(output *models.RunningOutput) {
 			defer wg.Done()
-			err := output.Write()
-			if err != nil {
-				log.Printf("E! Error writing to output [%s]: %s\n",
-					output.Name, err.Error())
-			}
+			writeOutput(output)
 		}(o)
 	}
 
 	wg.Wait()
 }
 
+// writeOutput writes the buffered metrics of a single output.
+func writeOutput(output *models.RunningOutput) {
+	err := output.Write()
+	if err != nil {
+		log.Printf("E! Error writing to output [%s]: %s\n",
+			output.Name, err.Error())
+	}
+}
+
+// outputFlusher flushes a single output on its own interval, or as soon as a
+// full batch is ready, until shutdown. Each output runs its own
+// outputFlusher so that a slow output does not delay writes to the others.
+func (a *Agent) outputFlusher(
+	shutdown chan struct{},
+	output *models.RunningOutput,
+) {
+	interval := a.Config.Agent.FlushInterval.Duration
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+	jitter := a.Config.Agent.FlushJitter.Duration
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	batchReady := output.BatchReady()
+
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+			internal.RandomSleep(jitter, shutdown)
+			writeOutput(output)
+		case <-batchReady:
+			writeOutput(output)
+		}
+	}
+}
+
+// route holds the outputs the metrics of an aggregator with outputs set are
+// written to, and the channel the aggregator sends them on.
+type route struct {
+	outputs []*models.RunningOutput
+	metrics chan telegraf.Metric
+}
+
+// aggregatorRoutes returns the routes of the aggregators with outputs set.
+func (a *Agent) aggregatorRoutes() map[*models.RunningAggregator]*route {
+	routes := make(map[*models.RunningAggregator]*route)
+	for _, agg := range a.Config.Aggregators {
+		if len(agg.Config.Outputs) == 0 {
+			continue
+		}
+
+		r := &route{metrics: make(chan telegraf.Metric, 100)}
+		for _, o := range a.Config.Outputs {
+			for _, name := range agg.Config.Outputs {
+				if o.Named(name) {
+					r.outputs = append(r.outputs, o)
+					break
+				}
+			}
+		}
+		// Undefined outputs are rejected when the config is loaded, unless
+		// outputs are filtered: then all of them may be filtered out.
+		if len(r.outputs) == 0 {
+			log.Printf("W! The outputs %v of aggregator %s are all excluded "+
+				"by the output filter, its metrics will be dropped\n",
+				agg.Config.Outputs, agg.Name())
+		}
+		routes[agg] = r
+	}
+	return routes
+}
+
+// writeAggregate applies the processors to a metric of an aggregator and
+// writes the result to the outputs.
+func (a *Agent) writeAggregate(
+	metric telegraf.Metric,
+	outputs []*models.RunningOutput,
+) {
+	metrics := []telegraf.Metric{metric}
+	for _, processor := range a.Config.Processors {
+		metrics = processor.Apply(metrics...)
+	}
+	for _, m := range metrics {
+		writeToOutputs(m, outputs)
+	}
+}
+
+// writeToOutputs adds the metric to each of the outputs.
+func writeToOutputs(m telegraf.Metric, outputs []*models.RunningOutput) {
+	if len(outputs) == 0 {
+		m.Drop()
+		return
+	}
+	for i, o := range outputs {
+		if i == len(outputs)-1 {
+			o.AddMetric(m)
+		} else {
+			o.AddMetric(m.Copy())
+		}
+	}
+}
+
 // flusher monitors the metrics input channel and flushes on the minimum interval
-func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric) error {
+func (a *Agent) flusher(
+	shutdown chan struct{},
+	metricC chan telegraf.Metric,
+	aggC chan telegraf.Metric,
+	routes map[*models.RunningAggregator]*route,
+) error {
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
 	// the flusher will flush after metrics are collected.
 	time.Sleep(time.Millisecond * 300)
@@ -269,24 +475,41 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 				return
 			case m := <-outMetricC:
 				// if dropOriginal is set to true, then we will only send this
-				// metric to the aggregators, not the outputs.
+				// metric to the aggregators, not the outputs. For aggregators
+				// with outputs set, it is only not sent to these outputs.
 				var dropOriginal bool
+				var dropped map[*models.RunningOutput]bool
 				if !m.IsAggregate() {
 					for _, agg := range a.Config.Aggregators {
 						if ok := agg.Add(m.Copy()); ok {
-							dropOriginal = true
+							r, routed := routes[agg]
+							if !routed {
+								dropOriginal = true
+								continue
+							}
+							if dropped == nil {
+								dropped = make(map[*models.RunningOutput]bool)
+							}
+							for _, o := range r.outputs {
+								dropped[o] = true
+							}
 						}
 					}
 				}
-				if !dropOriginal {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
-							o.AddMetric(m)
-						} else {
-							o.AddMetric(m.Copy())
+				if dropOriginal {
+					m.Drop()
+					continue
+				}
+				outputs := a.Config.Outputs
+				if dropped != nil {
+					outputs = nil
+					for _, o := range a.Config.Outputs {
+						if !dropped[o] {
+							outputs = append(outputs, o)
 						}
 					}
 				}
+				writeToOutputs(m, outputs)
 			}
 		}
 	}()
@@ -303,46 +526,49 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 				}
 				return
 			case metric := <-aggC:
-				metrics := []telegraf.Metric{metric}
-				for _, processor := range a.Config.Processors {
-					metrics = processor.Apply(metrics...)
-				}
-				for _, m := range metrics {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
-							o.AddMetric(m)
-						} else {
-							o.AddMetric(m.Copy())
-						}
-					}
-				}
+				a.writeAggregate(metric, a.Config.Outputs)
 			}
 		}
 	}()
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
-	semaphore := make(chan struct{}, 1)
+	for _, r := range routes {
+		wg.Add(1)
+		go func(r *route) {
+			defer wg.Done()
+			for {
+				select {
+				case <-shutdown:
+					if len(r.metrics) > 0 {
+						// keep going until the route is flushed
+						continue
+					}
+					return
+				case metric := <-r.metrics:
+					a.writeAggregate(metric, r.outputs)
+				}
+			}
+		}(r)
+	}
+
+	var flushWg sync.WaitGroup
+	flushWg.Add(len(a.Config.Outputs))
+	for _, o := range a.Config.Outputs {
+		go func(output *models.RunningOutput) {
+			defer flushWg.Done()
+			a.outputFlusher(shutdown, output)
+		}(o)
+	}
+
 	for {
 		select {
 		case <-shutdown:
 			log.Println("I! Hang on, flushing any cached metrics before shutdown")
-			// wait for outMetricC to get flushed before flushing outputs
+			// wait for outMetricC to get flushed and for any ongoing writes
+			// to finish before flushing outputs
 			wg.Wait()
+			flushWg.Wait()
 			a.flush()
 			return nil
-		case <-ticker.C:
-			go func() {
-				select {
-				case semaphore <- struct{}{}:
-					internal.RandomSleep(a.Config.Agent.FlushJitter.Duration, shutdown)
-					a.flush()
-					<-semaphore
-				default:
-					// skipping this flush because one is already happening
-					log.Println("W! Skipping a scheduled flush because there is" +
-						" already a flush ongoing.")
-				}
-			}()
 		case metric := <-metricC:
 			// NOTE potential bottleneck here as we put each metric through the
 			// processors serially.
@@ -367,24 +593,30 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
 	// channel shared between all input threads for accumulating metrics
-	metricC := make(chan telegraf.Metric, 100)
-	aggC := make(chan telegraf.Metric, 100)
+	metricC := a.metricC
+	aggC := a.aggC
 
 	// Start all ServicePlugins
 	for _, input := range a.Config.Inputs {
 		input.SetDefaultTags(a.Config.Tags)
 		switch p := input.Input.(type) {
 		case telegraf.ServiceInput:
-			acc := NewAccumulator(input, metricC)
-			// Service input plugins should set their own precision of their
-			// metrics.
-			acc.SetPrecision(time.Nanosecond, 0)
-			if err := p.Start(acc); err != nil {
-				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
-					input.Name(), err.Error())
-				return err
+			if !a.running[input] {
+				acc := NewAccumulator(input, metricC)
+				// Service input plugins should set their own precision of
+				// their metrics.
+				acc.SetPrecision(time.Nanosecond, 0)
+				if err := p.Start(acc); err != nil {
+					log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
+						input.Name(), err.Error())
+					return err
+				}
 			}
-			defer p.Stop()
+			defer func(input *models.RunningInput, p telegraf.ServiceInput) {
+				if !a.isHandedOver(input) {
+					p.Stop()
+				}
+			}(input, p)
 		}
 	}
 
@@ -394,10 +626,12 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		time.Sleep(time.Duration(i - (time.Now().UnixNano() % i)))
 	}
 
+	routes := a.aggregatorRoutes()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := a.flusher(shutdown, metricC, aggC); err != nil {
+		if err := a.flusher(shutdown, metricC, aggC, routes); err != nil {
 			log.Printf("E! Flusher routine failed, exiting: %s\n", err.Error())
 			close(shutdown)
 		}
@@ -407,7 +641,11 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	for _, aggregator := range a.Config.Aggregators {
 		go func(agg *models.RunningAggregator) {
 			defer wg.Done()
-			acc := NewAccumulator(agg, aggC)
+			metrics := aggC
+			if r, ok := routes[agg]; ok {
+				metrics = r.metrics
+			}
+			acc := NewAccumulator(agg, metrics)
 			acc.SetPrecision(a.Config.Agent.Precision.Duration,
 				a.Config.Agent.Interval.Duration)
 			agg.Run(acc, shutdown)
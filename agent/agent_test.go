@@ -1,9 +1,16 @@
 package agent
 
 import (
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/aggregators/minmax"
+	"github.com/influxdata/telegraf/plugins/outputs/discard"
 
 	// needing to load the plugins
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -109,3 +116,206 @@ func TestAgent_LoadOutput(t *testing.T) {
 	a, _ = NewAgent(c)
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
+
+func TestAgent_Reload(t *testing.T) {
+	c := config.NewConfig()
+	c.InputFilters = []string{"mysql", "redis"}
+	err := c.LoadConfig("../internal/config/testdata/telegraf-agent.toml")
+	assert.NoError(t, err)
+	a, _ := NewAgent(c)
+
+	c = config.NewConfig()
+	c.InputFilters = []string{"mysql"}
+	err = c.LoadConfig("../internal/config/testdata/telegraf-agent.toml")
+	assert.NoError(t, err)
+	next, err := a.Reload(c)
+	assert.NoError(t, err)
+
+	// The unchanged mysql input is taken over, redis is removed.
+	assert.Equal(t, 1, len(next.Config.Inputs))
+	mysql := next.Config.Inputs[0]
+	assert.Equal(t, "inputs.mysql", mysql.Name())
+	assert.True(t, next.running[mysql])
+	assert.True(t, a.isHandedOver(mysql))
+	for _, input := range a.Config.Inputs {
+		if input != mysql {
+			assert.False(t, a.isHandedOver(input))
+		}
+	}
+	assert.True(t, a.metricC == next.metricC)
+}
+
+func TestAgent_AggregatorRoutes(t *testing.T) {
+	c := config.NewConfig()
+	shortterm := models.NewRunningOutput("influxdb", &discard.Discard{},
+		&models.OutputConfig{Name: "influxdb", Alias: "shortterm"}, 0, 0)
+	longterm := models.NewRunningOutput("influxdb", &discard.Discard{},
+		&models.OutputConfig{Name: "influxdb", Alias: "longterm"}, 0, 0)
+	file := models.NewRunningOutput("file", &discard.Discard{},
+		&models.OutputConfig{Name: "file"}, 0, 0)
+	c.Outputs = append(c.Outputs, shortterm, longterm, file)
+
+	all := models.NewRunningAggregator(minmax.NewMinMax(),
+		&models.AggregatorConfig{Name: "minmax"})
+	routed := models.NewRunningAggregator(minmax.NewMinMax(),
+		&models.AggregatorConfig{Name: "minmax", Outputs: []string{"longterm", "file"}})
+	missing := models.NewRunningAggregator(minmax.NewMinMax(),
+		&models.AggregatorConfig{Name: "minmax", Outputs: []string{"foo"}})
+	c.Aggregators = append(c.Aggregators, all, routed, missing)
+
+	a, _ := NewAgent(c)
+	routes := a.aggregatorRoutes()
+	assert.Len(t, routes, 2)
+	assert.NotContains(t, routes, all)
+	assert.Equal(t, []*models.RunningOutput{longterm, file}, routes[routed].outputs)
+	assert.Empty(t, routes[missing].outputs)
+}
+
+func TestAgent_FlusherDropOriginal(t *testing.T) {
+	c := config.NewConfig()
+	shortterm := &recordingOutput{}
+	longterm := &recordingOutput{}
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("influxdb", shortterm,
+			&models.OutputConfig{Name: "influxdb", Alias: "shortterm"}, 1, 10),
+		models.NewRunningOutput("influxdb", longterm,
+			&models.OutputConfig{Name: "influxdb", Alias: "longterm"}, 1, 10))
+
+	routed := models.NewRunningAggregator(minmax.NewMinMax(),
+		&models.AggregatorConfig{
+			Name:         "minmax",
+			DropOriginal: true,
+			Outputs:      []string{"longterm"},
+		})
+	c.Aggregators = append(c.Aggregators, routed)
+
+	a, _ := NewAgent(c)
+	routes := a.aggregatorRoutes()
+	shutdown := make(chan struct{})
+	metricC := make(chan telegraf.Metric, 10)
+	aggC := make(chan telegraf.Metric, 10)
+	done := make(chan error)
+	go func() {
+		done <- a.flusher(shutdown, metricC, aggC, routes)
+	}()
+
+	m, _ := metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0))
+	metricC <- m
+	agg, _ := metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{"value_max": 1.0},
+		time.Unix(0, 0))
+	agg.SetAggregate(true)
+	routes[routed].metrics <- agg
+
+	// The original is dropped for the outputs of the aggregator only, and
+	// its aggregates are written to these outputs only.
+	for i := 0; i < 100; i++ {
+		if shortterm.len() == 1 && longterm.len() == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	close(shutdown)
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, []string{"cpu value=1 0\n"}, shortterm.written())
+	assert.Equal(t, []string{"cpu value_max=1 0\n"}, longterm.written())
+}
+
+// recordingOutput records the metrics written to it.
+func TestAgent_FlusherDropTracked(t *testing.T) {
+	c := config.NewConfig()
+	out := &recordingOutput{}
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("influxdb", out,
+			&models.OutputConfig{Name: "influxdb"}, 1, 10))
+	c.Processors = append(c.Processors, &models.RunningProcessor{
+		Name:      "drop",
+		Processor: &dropProcessor{name: "drop"},
+		Config:    &models.ProcessorConfig{Name: "drop"},
+	})
+
+	a, _ := NewAgent(c)
+	shutdown := make(chan struct{})
+	metricC := make(chan telegraf.Metric, 10)
+	aggC := make(chan telegraf.Metric, 10)
+	done := make(chan error)
+	go func() {
+		done <- a.flusher(shutdown, metricC, aggC, a.aggregatorRoutes())
+	}()
+	defer func() {
+		close(shutdown)
+		assert.NoError(t, <-done)
+	}()
+
+	// A tracked metric dropped by a processor is delivered, so that the
+	// input does not wait for it forever.
+	acc := NewAccumulator(&TestMetricMaker{}, metricC).WithTracking(1)
+	m, _ := metric.New("drop",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0))
+	id := acc.AddTrackingMetricGroup([]telegraf.Metric{m})
+
+	select {
+	case info := <-acc.Delivered():
+		assert.Equal(t, id, info.ID())
+		assert.True(t, info.Delivered())
+	case <-time.After(5 * time.Second):
+		t.Fatal("tracked metric dropped by a processor was not delivered")
+	}
+	assert.Zero(t, out.len())
+}
+
+// dropProcessor drops the metrics with the given name.
+type dropProcessor struct {
+	name string
+}
+
+func (p *dropProcessor) Description() string  { return "" }
+func (p *dropProcessor) SampleConfig() string { return "" }
+
+func (p *dropProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	var out []telegraf.Metric
+	for _, m := range in {
+		if m.Name() != p.name {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+type recordingOutput struct {
+	sync.Mutex
+	metrics []string
+}
+
+func (o *recordingOutput) Connect() error       { return nil }
+func (o *recordingOutput) Close() error         { return nil }
+func (o *recordingOutput) Description() string  { return "" }
+func (o *recordingOutput) SampleConfig() string { return "" }
+
+func (o *recordingOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	for _, m := range metrics {
+		o.metrics = append(o.metrics, m.String())
+	}
+	return nil
+}
+
+func (o *recordingOutput) len() int {
+	o.Lock()
+	defer o.Unlock()
+	return len(o.metrics)
+}
+
+func (o *recordingOutput) written() []string {
+	o.Lock()
+	defer o.Unlock()
+	return o.metrics
+}
@@ -21,6 +21,8 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
+	secretfile "github.com/influxdata/telegraf/plugins/secretstores/file"
 	"github.com/kardianos/service"
 )
 
@@ -32,6 +34,16 @@ var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
+var fConfigPollInterval = flag.Duration("config-poll-interval", 0,
+	"interval at which to check a --config URL for changes, ie, '5m'")
+var fConfigSSLCA = flag.String("config-ssl-ca", "",
+	"CA file to verify the server of a --config URL")
+var fConfigSSLCert = flag.String("config-ssl-cert", "",
+	"client certificate file for a --config URL")
+var fConfigSSLKey = flag.String("config-ssl-key", "",
+	"client key file for a --config URL")
+var fConfigInsecureSkipVerify = flag.Bool("config-insecure-skip-verify", false,
+	"skip verification of the server of a --config URL")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
 var fVersion = flag.Bool("version", false, "display the version")
@@ -83,8 +95,18 @@ The commands & flags are:
 
   config              print out full sample configuration to stdout
   version             print the version to stdout
-
-  --config <file>     configuration file to load
+  encrypt-secrets <key file>
+                      encrypt the JSON object of secrets read from stdin
+                      for the file secret store and write it to stdout
+
+  --config <file>     configuration file or http(s) URL to load
+  --config-poll-interval
+                      interval at which to check a --config URL for changes
+                      and reload the configuration, ie, '5m'
+  --config-ssl-ca, --config-ssl-cert, --config-ssl-key
+                      TLS files used to fetch a --config URL
+  --config-insecure-skip-verify
+                      skip verification of the server of a --config URL
   --test              gather metrics once, print them to stdout, and exit
   --config-directory  directory containing additional *.conf files
   --input-filter      filter the input plugins to enable, separator is :
@@ -111,12 +133,64 @@ Examples:
   # run telegraf, enabling the cpu & memory input, and influxdb output plugins
   telegraf --config telegraf.conf --input-filter cpu:mem --output-filter influxdb
 
+  # run telegraf with a central configuration, checked for changes every 5m
+  TELEGRAF_CONFIG_TOKEN=secret telegraf --config https://config.example.com/telegraf.conf --config-poll-interval 5m
+
   # run telegraf with pprof
   telegraf --config telegraf.conf --pprof-addr localhost:6060
+
+  # create an encrypted secret store
+  openssl rand -hex 32 > secrets.key
+  telegraf encrypt-secrets secrets.key < secrets.json > secrets.enc
 `
 
 var stop chan struct{}
 
+// loadConfig loads and validates the configuration files.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	c.Remote = config.Remote{
+		Token:              os.Getenv("TELEGRAF_CONFIG_TOKEN"),
+		SSLCA:              *fConfigSSLCA,
+		SSLCert:            *fConfigSSLCert,
+		SSLKey:             *fConfigSSLKey,
+		InsecureSkipVerify: *fConfigInsecureSkipVerify,
+	}
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !*fTest && len(c.Outputs) == 0 {
+		return nil, fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
+	}
+	if err := c.CheckAggregatorOutputs(); err != nil {
+		return nil, err
+	}
+
+	if int64(c.Agent.Interval.Duration) <= 0 {
+		return nil, fmt.Errorf("Agent interval must be positive, found %s",
+			c.Agent.Interval.Duration)
+	}
+
+	if int64(c.Agent.FlushInterval.Duration) <= 0 {
+		return nil, fmt.Errorf("Agent flush_interval must be positive; found %s",
+			c.Agent.Interval.Duration)
+	}
+	return c, nil
+}
+
 func reloadLoop(
 	stop chan struct{},
 	inputFilters []string,
@@ -124,48 +198,53 @@ func reloadLoop(
 	aggregatorFilters []string,
 	processorFilters []string,
 ) {
-	reload := make(chan bool, 1)
-	reload <- true
-	for <-reload {
-		reload <- false
-
-		// If no other options are specified, load the config file and run.
-		c := config.NewConfig()
-		c.OutputFilters = outputFilters
-		c.InputFilters = inputFilters
-		err := c.LoadConfig(*fConfig)
+	// If no other options are specified, load the config file and run.
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
+
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
+	c.UseSecretStores()
+
+	if *fTest {
+		logger.SetupLogging(
+			ag.Config.Agent.Debug || *fDebug,
+			ag.Config.Agent.Quiet || *fQuiet,
+			ag.Config.Agent.Logfile,
+		)
+		err = ag.Test()
 		if err != nil {
 			log.Fatal("E! " + err.Error())
 		}
+		os.Exit(0)
+	}
 
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
-			if err != nil {
-				log.Fatal("E! " + err.Error())
-			}
-		}
-		if !*fTest && len(c.Outputs) == 0 {
-			log.Fatalf("E! Error: no outputs found, did you provide a valid config file?")
-		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("E! Error: no inputs found, did you provide a valid config file?")
-		}
+	if *fPidfile != "" {
+		f, err := os.OpenFile(*fPidfile, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("E! Unable to create pidfile: %s", err)
+		} else {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
 
-		if int64(c.Agent.Interval.Duration) <= 0 {
-			log.Fatalf("E! Agent interval must be positive, found %s",
-				c.Agent.Interval.Duration)
-		}
+			f.Close()
 
-		if int64(c.Agent.FlushInterval.Duration) <= 0 {
-			log.Fatalf("E! Agent flush_interval must be positive; found %s",
-				c.Agent.Interval.Duration)
+			defer func() {
+				err := os.Remove(*fPidfile)
+				if err != nil {
+					log.Printf("E! Unable to remove pidfile: %s", err)
+				}
+			}()
 		}
+	}
 
-		ag, err := agent.NewAgent(c)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
 
+	for ag != nil {
 		// Setup logging
 		logger.SetupLogging(
 			ag.Config.Agent.Debug || *fDebug,
@@ -173,63 +252,78 @@ func reloadLoop(
 			ag.Config.Agent.Logfile,
 		)
 
-		if *fTest {
-			err = ag.Test()
-			if err != nil {
-				log.Fatal("E! " + err.Error())
-			}
-			os.Exit(0)
-		}
-
 		err = ag.Connect()
 		if err != nil {
 			log.Fatal("E! " + err.Error())
 		}
 
+		// On SIGHUP the new configuration is loaded while the current agent
+		// is still running, so that the plugins which did not change are
+		// handed over to the next agent instead of being restarted. The
+		// current configuration stays in effect if the new one is invalid.
 		shutdown := make(chan struct{})
-		signals := make(chan os.Signal)
-		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
-		go func() {
-			select {
-			case sig := <-signals:
-				if sig == os.Interrupt {
-					close(shutdown)
+		next := make(chan *agent.Agent, 1)
+
+		// A change of a remote configuration reloads it just like SIGHUP.
+		// Changes are detected against the contents the agent was loaded
+		// from.
+		var changed <-chan struct{}
+		if *fConfigPollInterval > 0 && config.IsURL(*fConfig) {
+			changed = ag.Config.Remote.Watch(*fConfig,
+				ag.Config.RemoteSum(*fConfig), *fConfigPollInterval, shutdown)
+		}
+		go func(ag *agent.Agent) {
+			reload := func() bool {
+				log.Printf("I! Reloading Telegraf config\n")
+				c, err := loadConfig(inputFilters, outputFilters)
+				if err != nil {
+					log.Printf("E! Not reloading config: %s", err)
+					return false
 				}
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config\n")
-					<-reload
-					reload <- true
-					close(shutdown)
+				na, err := ag.Reload(c)
+				if err != nil {
+					log.Printf("E! Not reloading config: %s", err)
+					return false
 				}
-			case <-stop:
+				c.UseSecretStores()
+				next <- na
 				close(shutdown)
+				return true
 			}
-		}()
-
-		log.Printf("I! Starting Telegraf %s\n", displayVersion())
-		log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
-		log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
-		log.Printf("I! Tags enabled: %s", c.ListTags())
-
-		if *fPidfile != "" {
-			f, err := os.OpenFile(*fPidfile, os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
-				log.Printf("E! Unable to create pidfile: %s", err)
-			} else {
-				fmt.Fprintf(f, "%d\n", os.Getpid())
 
-				f.Close()
-
-				defer func() {
-					err := os.Remove(*fPidfile)
-					if err != nil {
-						log.Printf("E! Unable to remove pidfile: %s", err)
+			for {
+				select {
+				case sig := <-signals:
+					if sig == os.Interrupt {
+						close(shutdown)
+						return
+					}
+					if sig == syscall.SIGHUP && reload() {
+						return
 					}
-				}()
+				case <-changed:
+					if reload() {
+						return
+					}
+				case <-stop:
+					close(shutdown)
+					return
+				}
 			}
-		}
+		}(ag)
+
+		log.Printf("I! Starting Telegraf %s\n", displayVersion())
+		log.Printf("I! Loaded outputs: %s", strings.Join(ag.Config.OutputNames(), " "))
+		log.Printf("I! Loaded inputs: %s", strings.Join(ag.Config.InputNames(), " "))
+		log.Printf("I! Tags enabled: %s", ag.Config.ListTags())
 
 		ag.Run(shutdown)
+
+		select {
+		case ag = <-next:
+		default:
+			ag = nil
+		}
 	}
 }
 
@@ -322,6 +416,15 @@ func main() {
 				processorFilters,
 			)
 			return
+		case "encrypt-secrets":
+			if len(args) != 2 {
+				usageExit(1)
+			}
+			err := secretfile.EncryptSecrets(args[1], os.Stdin, os.Stdout)
+			if err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			return
 		}
 	}
 
@@ -0,0 +1,652 @@
+package buffer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Fsync policies for the DiskBuffer.
+const (
+	// FsyncAlways syncs the segment files after every Add and commit.
+	FsyncAlways = "always"
+	// FsyncInterval syncs the segment files at most once per FsyncInterval.
+	FsyncInterval = "interval"
+	// FsyncNever leaves syncing to the operating system.
+	FsyncNever = "never"
+)
+
+const (
+	// DefaultSegmentSize is the size at which a new segment file is started.
+	DefaultSegmentSize = 16 * 1024 * 1024
+
+	// DefaultFsyncInterval is used with the FsyncInterval policy when no
+	// interval is configured.
+	DefaultFsyncInterval = time.Second
+
+	segmentExt   = ".seg"
+	cursorFile   = "cursor"
+	headerLength = 8
+)
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// DiskConfig contains the settings of a DiskBuffer.
+type DiskConfig struct {
+	// Directory is where the segment files are stored. It is created if it
+	// does not exist.
+	Directory string
+
+	// MaxSize is the maximum number of bytes kept on disk. When it is
+	// exceeded the oldest segment is dropped. Zero means no limit.
+	MaxSize int64
+
+	// SegmentSize is the size in bytes at which a new segment file is
+	// started.
+	SegmentSize int64
+
+	// Fsync is one of FsyncAlways, FsyncInterval or FsyncNever.
+	Fsync string
+
+	// FsyncInterval is the minimum time between syncs when Fsync is
+	// FsyncInterval.
+	FsyncInterval time.Duration
+}
+
+// segment is a single append-only file of length-prefixed metric records.
+type segment struct {
+	id   uint64
+	path string
+	// size is the number of bytes in the file.
+	size int64
+	// offset is the position of the first unread record.
+	offset int64
+	// count is the number of unread records.
+	count int
+}
+
+// position is the location of a record in the segment files.
+type position struct {
+	id     uint64
+	offset int64
+}
+
+// inflight is a batch returned by Peek. done is set once it is committed.
+type inflight struct {
+	end  position
+	done bool
+}
+
+// DiskBuffer is a buffer that stores metrics in segment files so that they
+// survive a restart of the agent. Metrics found in the directory when the
+// buffer is opened are replayed before any newly added metrics.
+type DiskBuffer struct {
+	conf DiskConfig
+
+	mu       sync.Mutex
+	segments []*segment
+	writer   *os.File
+	nextID   uint64
+	length   int
+	size     int64
+	replayed int
+	dirty    bool
+	lastSync time.Time
+
+	// committed is the position up to which all batches have been
+	// committed, it is persisted in the cursor file.
+	committed position
+	inflight  []*inflight
+
+	// tracked are the metrics tracked for delivery that are on disk, by the
+	// position of their record. Peek returns them instead of the metrics
+	// read from disk, so that they are only accepted once written by the
+	// output.
+	tracked map[position]telegraf.Metric
+}
+
+// NewDiskBuffer opens the DiskBuffer stored in conf.Directory, creating it if
+// it does not exist yet.
+func NewDiskBuffer(conf DiskConfig) (*DiskBuffer, error) {
+	if conf.Directory == "" {
+		return nil, errors.New("disk buffer directory is required")
+	}
+	if conf.SegmentSize <= 0 {
+		conf.SegmentSize = DefaultSegmentSize
+	}
+	// Keep at least two segments within the size limit so that dropping the
+	// oldest one never discards the metrics currently being added.
+	if conf.MaxSize > 0 && conf.SegmentSize > conf.MaxSize/2 {
+		conf.SegmentSize = conf.MaxSize / 2
+	}
+	switch conf.Fsync {
+	case "":
+		conf.Fsync = FsyncInterval
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, fmt.Errorf("invalid fsync policy %q, must be one of %q, %q or %q",
+			conf.Fsync, FsyncAlways, FsyncInterval, FsyncNever)
+	}
+	if conf.FsyncInterval <= 0 {
+		conf.FsyncInterval = DefaultFsyncInterval
+	}
+
+	if err := os.MkdirAll(conf.Directory, 0750); err != nil {
+		return nil, err
+	}
+
+	b := &DiskBuffer{
+		conf:     conf,
+		lastSync: time.Now(),
+		tracked:  make(map[position]telegraf.Metric),
+	}
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+	b.replayed = b.length
+	return b, nil
+}
+
+// open loads the existing segments and read cursor from the directory.
+func (b *DiskBuffer) open() error {
+	cursorID, cursorOffset, err := b.readCursor()
+	if err != nil {
+		return err
+	}
+	b.committed = position{id: cursorID, offset: cursorOffset}
+
+	paths, err := filepath.Glob(filepath.Join(b.conf.Directory, "*"+segmentExt))
+	if err != nil {
+		return err
+	}
+
+	var segments []*segment
+	for _, path := range paths {
+		id, err := strconv.ParseUint(
+			strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, &segment{id: id, path: path})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].id < segments[j].id
+	})
+
+	for _, s := range segments {
+		if s.id >= b.nextID {
+			b.nextID = s.id + 1
+		}
+		// Segments before the cursor have already been read completely.
+		if s.id < cursorID {
+			os.Remove(s.path)
+			continue
+		}
+		if s.id == cursorID {
+			s.offset = cursorOffset
+		}
+		if err := scanSegment(s); err != nil {
+			return err
+		}
+		if s.count == 0 {
+			os.Remove(s.path)
+			continue
+		}
+		b.segments = append(b.segments, s)
+		b.length += s.count
+		b.size += s.size
+	}
+	return nil
+}
+
+// scanSegment counts the unread records in a segment. A partially written
+// or corrupt record at the end of the file, as left by a crash, is truncated
+// away.
+func scanSegment(s *segment) error {
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	var pos int64
+	for {
+		n, err := readRecord(r, nil, info.Size()-pos)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("W! Truncating disk buffer segment %s at offset %d: %s",
+				s.path, pos, err)
+			if err := f.Truncate(pos); err != nil {
+				return err
+			}
+			break
+		}
+		if pos >= s.offset {
+			s.count++
+		}
+		pos += n
+	}
+	s.size = pos
+	if s.offset > s.size {
+		s.offset = s.size
+	}
+	return nil
+}
+
+// readRecord reads a single record from r. If buf is not nil the payload is
+// read into it, otherwise it is discarded after its checksum is verified. It
+// returns the number of bytes consumed. A record longer than the remaining
+// bytes of the file is corrupt.
+func readRecord(r *bufio.Reader, buf *[]byte, remaining int64) (int64, error) {
+	var header [headerLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, errCorruptRecord
+		}
+		return 0, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if int64(length) > remaining-headerLength {
+		return 0, errCorruptRecord
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, errCorruptRecord
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return 0, errCorruptRecord
+	}
+	if buf != nil {
+		*buf = payload
+	}
+	return int64(headerLength) + int64(length), nil
+}
+
+// IsEmpty returns true if DiskBuffer is empty.
+func (b *DiskBuffer) IsEmpty() bool {
+	return b.Len() == 0
+}
+
+// Len returns the number of metrics in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.length
+}
+
+// Size returns the number of bytes the buffer currently uses on disk.
+func (b *DiskBuffer) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// Replayed returns the number of metrics that were found on disk when the
+// buffer was opened.
+func (b *DiskBuffer) Replayed() int {
+	return b.replayed
+}
+
+// Add adds metrics to the buffer. If the buffer is over its size limit the
+// oldest segment is dropped.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, m := range metrics {
+		MetricsWritten.Incr(1)
+		pos, err := b.write(encodeMetric(m))
+		if err != nil {
+			log.Printf("E! Unable to write metric to disk buffer %s: %s",
+				b.conf.Directory, err)
+			MetricsDropped.Incr(1)
+			m.Reject()
+			continue
+		}
+		if metric.IsTracked(m) {
+			b.tracked[pos] = m
+		}
+	}
+
+	for b.conf.MaxSize > 0 && b.size > b.conf.MaxSize && len(b.segments) > 1 {
+		b.dropOldest()
+	}
+	b.sync(false)
+}
+
+// write appends a record to the write segment and returns its position.
+func (b *DiskBuffer) write(payload []byte) (position, error) {
+	length := int64(headerLength + len(payload))
+
+	tail := b.tail()
+	if tail == nil || (tail.size > 0 && tail.size+length > b.conf.SegmentSize) {
+		if err := b.rotate(); err != nil {
+			return position{}, err
+		}
+		tail = b.tail()
+	}
+	pos := position{id: tail.id, offset: tail.size}
+
+	record := make([]byte, length)
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[headerLength:], payload)
+
+	if _, err := b.writer.Write(record); err != nil {
+		// Roll back whatever part of the record made it to the file.
+		b.writer.Truncate(tail.size)
+		return position{}, err
+	}
+	tail.size += length
+	tail.count++
+	b.size += length
+	b.length++
+	b.dirty = true
+	return pos, nil
+}
+
+// tail returns the segment currently open for writing, or nil.
+func (b *DiskBuffer) tail() *segment {
+	if b.writer == nil || len(b.segments) == 0 {
+		return nil
+	}
+	return b.segments[len(b.segments)-1]
+}
+
+// rotate closes the current write segment and starts a new one.
+func (b *DiskBuffer) rotate() error {
+	if err := b.closeWriter(); err != nil {
+		return err
+	}
+
+	s := &segment{id: b.nextID}
+	s.path = filepath.Join(b.conf.Directory, fmt.Sprintf("%020d%s", s.id, segmentExt))
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	b.nextID++
+	b.writer = f
+	b.segments = append(b.segments, s)
+	return nil
+}
+
+func (b *DiskBuffer) closeWriter() error {
+	if b.writer == nil {
+		return nil
+	}
+	err := b.writer.Sync()
+	if cerr := b.writer.Close(); err == nil {
+		err = cerr
+	}
+	b.writer = nil
+	return err
+}
+
+// dropOldest removes the oldest segment, discarding its unread metrics.
+func (b *DiskBuffer) dropOldest() {
+	s := b.segments[0]
+	MetricsDropped.Incr(int64(s.count))
+	b.rejectTracked(s.id)
+	b.removeHead()
+	b.writeCursor()
+}
+
+// rejectTracked rejects the tracked metrics of the segment id, whose unread
+// metrics are dropped.
+func (b *DiskBuffer) rejectTracked(id uint64) {
+	for pos, m := range b.tracked {
+		if pos.id == id {
+			m.Reject()
+			delete(b.tracked, pos)
+		}
+	}
+}
+
+// removeHead deletes the oldest segment from disk.
+func (b *DiskBuffer) removeHead() {
+	s := b.segments[0]
+	if len(b.segments) == 1 {
+		b.closeWriter()
+	}
+	if err := os.Remove(s.path); err != nil {
+		log.Printf("E! Unable to remove disk buffer segment %s: %s", s.path, err)
+	}
+	b.length -= s.count
+	b.size -= s.size
+	b.segments = b.segments[1:]
+}
+
+// Batch returns a batch of metrics of size batchSize, removing them from the
+// buffer. The batch will be smaller than batchSize if there are not enough
+// metrics in the buffer.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	out, commit := b.Peek(batchSize)
+	commit()
+	return out
+}
+
+// Peek returns a batch of metrics of size batchSize like Batch does, but the
+// metrics stay on disk until the returned commit function is called. If the
+// agent stops before that, they are replayed when the buffer is opened again.
+// Batches may be committed in any order, the metrics are only removed from
+// disk once all batches before them are committed too.
+func (b *DiskBuffer) Peek(batchSize int) ([]telegraf.Metric, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]telegraf.Metric, 0, min(b.length, batchSize))
+	for _, s := range b.segments {
+		if len(out) >= batchSize {
+			break
+		}
+		if s.count == 0 {
+			continue
+		}
+		var err error
+		out, err = b.readSegment(s, out, batchSize-len(out))
+		if err != nil {
+			log.Printf("E! Unable to read disk buffer segment %s, dropping "+
+				"%d metrics: %s", s.path, s.count, err)
+			MetricsDropped.Incr(int64(s.count))
+			b.rejectTracked(s.id)
+			b.length -= s.count
+			s.count = 0
+		}
+	}
+
+	f := &inflight{end: b.readPosition()}
+	b.inflight = append(b.inflight, f)
+	return out, func() { b.commit(f) }
+}
+
+// readPosition returns the position of the first unread record.
+func (b *DiskBuffer) readPosition() position {
+	for _, s := range b.segments {
+		if s.count > 0 {
+			return position{id: s.id, offset: s.offset}
+		}
+	}
+	if len(b.segments) > 0 {
+		s := b.segments[len(b.segments)-1]
+		return position{id: s.id, offset: s.offset}
+	}
+	return position{id: b.nextID}
+}
+
+// commit marks a batch returned by Peek as handled, and removes the metrics
+// of all batches committed so far from disk.
+func (b *DiskBuffer) commit(f *inflight) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if f.done {
+		return
+	}
+	f.done = true
+	for len(b.inflight) > 0 && b.inflight[0].done {
+		b.committed = b.inflight[0].end
+		b.inflight = b.inflight[1:]
+	}
+
+	for len(b.segments) > 0 {
+		s := b.segments[0]
+		if s.id > b.committed.id ||
+			(s.id == b.committed.id && (s.count > 0 || b.committed.offset < s.size)) {
+			break
+		}
+		b.removeHead()
+	}
+
+	b.writeCursor()
+	b.sync(false)
+}
+
+// readSegment appends up to n metrics from the segment to out.
+func (b *DiskBuffer) readSegment(
+	s *segment,
+	out []telegraf.Metric,
+	n int,
+) ([]telegraf.Metric, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return out, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return out, err
+	}
+
+	r := bufio.NewReader(f)
+	var payload []byte
+	for i := 0; i < n && s.count > 0; i++ {
+		pos := position{id: s.id, offset: s.offset}
+		length, err := readRecord(r, &payload, s.size-s.offset)
+		if err != nil {
+			return out, err
+		}
+		s.offset += length
+		s.count--
+		b.length--
+
+		if m, ok := b.tracked[pos]; ok {
+			delete(b.tracked, pos)
+			out = append(out, m)
+			continue
+		}
+		m, err := decodeMetric(payload)
+		if err != nil {
+			log.Printf("E! Dropping metric from disk buffer %s: %s",
+				b.conf.Directory, err)
+			MetricsDropped.Incr(1)
+			continue
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// readCursor returns the segment id and offset of the first unread record.
+func (b *DiskBuffer) readCursor() (uint64, int64, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(b.conf.Directory, cursorFile))
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var id uint64
+	var offset int64
+	if _, err := fmt.Sscanf(string(contents), "%d %d", &id, &offset); err != nil {
+		return 0, 0, fmt.Errorf("invalid disk buffer cursor in %s: %s",
+			b.conf.Directory, err)
+	}
+	return id, offset, nil
+}
+
+// writeCursor persists the position of the first record that has not been
+// committed. The file is replaced atomically so that a crash leaves either the
+// old or the new cursor in place.
+func (b *DiskBuffer) writeCursor() {
+	id, offset := b.committed.id, b.committed.offset
+
+	path := filepath.Join(b.conf.Directory, cursorFile)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err == nil {
+		_, err = fmt.Fprintf(f, "%d %d\n", id, offset)
+		if err == nil && b.conf.Fsync == FsyncAlways {
+			err = f.Sync()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		log.Printf("E! Unable to write disk buffer cursor %s: %s", path, err)
+	}
+}
+
+// sync flushes the write segment to disk according to the fsync policy. If
+// force is true the segment is synced regardless of the policy.
+func (b *DiskBuffer) sync(force bool) {
+	if b.writer == nil || !b.dirty {
+		return
+	}
+	switch {
+	case force, b.conf.Fsync == FsyncAlways:
+	case b.conf.Fsync == FsyncInterval && time.Since(b.lastSync) >= b.conf.FsyncInterval:
+	default:
+		return
+	}
+
+	if err := b.writer.Sync(); err != nil {
+		log.Printf("E! Unable to sync disk buffer %s: %s", b.conf.Directory, err)
+		return
+	}
+	b.dirty = false
+	b.lastSync = time.Now()
+}
+
+// Close syncs and closes the segment files. The buffer must not be used
+// after it is closed. Tracked metrics still on disk are neither accepted nor
+// rejected: they are written by the next run, once the buffer is opened
+// again.
+func (b *DiskBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sync(true)
+	b.writeCursor()
+	return b.closeWriter()
+}
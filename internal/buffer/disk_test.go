@@ -0,0 +1,293 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskBuffer(t *testing.T, dir string) *DiskBuffer {
+	b, err := NewDiskBuffer(DiskConfig{Directory: dir, Fsync: FsyncAlways})
+	require.NoError(t, err)
+	return b
+}
+
+func TestDiskBufferBasicFuncs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir)
+	defer b.Close()
+	MetricsDropped.Set(0)
+	MetricsWritten.Set(0)
+
+	assert.True(t, b.IsEmpty())
+	assert.Zero(t, b.Len())
+	assert.Zero(t, b.Replayed())
+
+	b.Add(metricList...)
+	assert.False(t, b.IsEmpty())
+	assert.Equal(t, 5, b.Len())
+	assert.True(t, b.Size() > 0)
+	assert.Equal(t, int64(5), MetricsWritten.Get())
+
+	batch := b.Batch(3)
+	require.Len(t, batch, 3)
+	for i, m := range batch {
+		assert.Equal(t, metricList[i].String(), m.String())
+	}
+	assert.Equal(t, 2, b.Len())
+
+	batch = b.Batch(10)
+	require.Len(t, batch, 2)
+	assert.Equal(t, metricList[4].String(), batch[1].String())
+	assert.True(t, b.IsEmpty())
+	assert.Zero(t, b.Size())
+	assert.Zero(t, MetricsDropped.Get())
+}
+
+func TestDiskBufferReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir)
+	b.Add(metricList...)
+	require.Len(t, b.Batch(2), 2)
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir)
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, 3, b.Replayed())
+
+	// New metrics are returned after the replayed ones.
+	b.Add(metricList[0])
+	batch := b.Batch(10)
+	require.Len(t, batch, 4)
+	assert.Equal(t, metricList[2].String(), batch[0].String())
+	assert.Equal(t, metricList[4].String(), batch[2].String())
+	assert.Equal(t, metricList[0].String(), batch[3].String())
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir)
+	defer b.Close()
+	assert.True(t, b.IsEmpty())
+	assert.Zero(t, b.Replayed())
+}
+
+func TestDiskBufferTruncatesPartialRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir)
+	b.Add(metricList...)
+	require.NoError(t, b.Close())
+
+	// Simulate a crash in the middle of writing a record.
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	f, err := os.OpenFile(paths[0], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 1, 0, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b = newTestDiskBuffer(t, dir)
+	defer b.Close()
+	assert.Equal(t, 5, b.Len())
+	assert.Len(t, b.Batch(10), 5)
+}
+
+func TestDiskBufferCorruptLength(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir)
+	b.Add(metricList...)
+	require.NoError(t, b.Close())
+
+	// A record header claiming a length beyond the end of the file.
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	f, err := os.OpenFile(paths[0], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b = newTestDiskBuffer(t, dir)
+	defer b.Close()
+	assert.Equal(t, 5, b.Len())
+	assert.Len(t, b.Batch(10), 5)
+}
+
+func TestDiskBufferPeekCommit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir)
+	b.Add(metricList...)
+	first, commitFirst := b.Peek(2)
+	require.Len(t, first, 2)
+	second, commitSecond := b.Peek(10)
+	require.Len(t, second, 3)
+	assert.True(t, b.IsEmpty())
+
+	// The first batch is still being written, so nothing is removed yet.
+	commitSecond()
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir)
+	assert.Equal(t, 5, b.Len())
+	first, commitFirst = b.Peek(2)
+	require.Len(t, first, 2)
+	commitFirst()
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir)
+	defer b.Close()
+	assert.Equal(t, 3, b.Len())
+	batch := b.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, metricList[2].String(), batch[0].String())
+	assert.Zero(t, b.Size())
+}
+
+func TestDiskBufferMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	record := int64(headerLength + len(encodeMetric(metricList[0])))
+	b, err := NewDiskBuffer(DiskConfig{
+		Directory: dir,
+		MaxSize:   record * 4,
+		Fsync:     FsyncNever,
+	})
+	require.NoError(t, err)
+	defer b.Close()
+	MetricsDropped.Set(0)
+
+	for i := 0; i < 4; i++ {
+		b.Add(metricList...)
+	}
+	assert.True(t, b.Size() <= record*4)
+	assert.True(t, MetricsDropped.Get() > 0)
+	assert.Equal(t, int64(b.Len())+MetricsDropped.Get(), int64(20))
+
+	// The newest metrics are kept.
+	batch := b.Batch(100)
+	require.NotEmpty(t, batch)
+	assert.Equal(t, metricList[4].String(), batch[len(batch)-1].String())
+}
+
+func TestDiskBufferTracking(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var infos []telegraf.DeliveryInfo
+	notify := func(info telegraf.DeliveryInfo) {
+		infos = append(infos, info)
+	}
+
+	record := int64(headerLength + len(encodeMetric(metricList[0])))
+	b, err := NewDiskBuffer(DiskConfig{
+		Directory:   dir,
+		MaxSize:     record * 4,
+		SegmentSize: record,
+		Fsync:       FsyncNever,
+	})
+	require.NoError(t, err)
+	defer b.Close()
+
+	// Tracked metrics are not delivered once on disk, but once the output
+	// has written the metrics returned by Peek.
+	group, _ := metric.WithGroupTracking(metricList[:2], notify)
+	b.Add(group...)
+	assert.Empty(t, infos)
+	batch, commit := b.Peek(10)
+	commit()
+	require.Len(t, batch, 2)
+	assert.Empty(t, infos)
+	for _, m := range batch {
+		m.Accept()
+	}
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Delivered())
+
+	// Metrics dropped from a full buffer are rejected.
+	group, _ = metric.WithGroupTracking(metricList[:1], notify)
+	b.Add(group...)
+	b.Add(metricList...)
+	require.Len(t, infos, 2)
+	assert.False(t, infos[1].Delivered())
+}
+
+func TestDiskBufferInvalidFsync(t *testing.T) {
+	_, err := NewDiskBuffer(DiskConfig{Directory: "/tmp", Fsync: "sometimes"})
+	assert.Error(t, err)
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	now := time.Unix(0, 1518640000123456789)
+	m, err := metric.New("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{
+			"usage":   42.5,
+			"count":   int64(-3),
+			"message": "line one\nline \"two\"",
+			"ok":      true,
+		},
+		now,
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	m.SetAggregate(true)
+
+	out, err := decodeMetric(encodeMetric(m))
+	require.NoError(t, err)
+	assert.Equal(t, m.Name(), out.Name())
+	assert.Equal(t, m.Tags(), out.Tags())
+	assert.Equal(t, m.Fields(), out.Fields())
+	assert.Equal(t, now.UnixNano(), out.UnixNano())
+	assert.Equal(t, telegraf.Counter, out.Type())
+	assert.True(t, out.IsAggregate())
+
+	_, err = decodeMetric(encodeMetric(m)[:10])
+	assert.Error(t, err)
+}
+
+func BenchmarkDiskBufferAdd(b *testing.B) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	buf, err := NewDiskBuffer(DiskConfig{Directory: dir, Fsync: FsyncNever})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer buf.Close()
+
+	m := testutil.TestMetric(1, "mymetric")
+	for n := 0; n < b.N; n++ {
+		buf.Add(m)
+	}
+}
@@ -0,0 +1,196 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Field value type markers used in the record encoding.
+const (
+	fieldFloat  byte = 'f'
+	fieldInt    byte = 'i'
+	fieldUint   byte = 'u'
+	fieldString byte = 's'
+	fieldBool   byte = 'b'
+)
+
+// aggregateFlag is set in the flags byte of a record when the metric was
+// produced by an aggregator.
+const aggregateFlag byte = 0x80
+
+// encodeMetric encodes a metric into the binary record format used by the
+// DiskBuffer segment files. Unlike line protocol, the encoding preserves the
+// value type of the metric and round-trips string fields containing newlines.
+func encodeMetric(m telegraf.Metric) []byte {
+	e := &encoder{}
+
+	flags := byte(m.Type())
+	if m.IsAggregate() {
+		flags |= aggregateFlag
+	}
+	e.buf.WriteByte(flags)
+	e.putVarint(m.UnixNano())
+	e.putString(m.Name())
+
+	tags := m.Tags()
+	e.putUvarint(uint64(len(tags)))
+	for k, v := range tags {
+		e.putString(k)
+		e.putString(v)
+	}
+
+	fields := m.Fields()
+	e.putUvarint(uint64(len(fields)))
+	for k, v := range fields {
+		e.putString(k)
+		switch v := v.(type) {
+		case float64:
+			e.buf.WriteByte(fieldFloat)
+			e.putUvarint(math.Float64bits(v))
+		case int64:
+			e.buf.WriteByte(fieldInt)
+			e.putVarint(v)
+		case uint64:
+			e.buf.WriteByte(fieldUint)
+			e.putUvarint(v)
+		case string:
+			e.buf.WriteByte(fieldString)
+			e.putString(v)
+		case bool:
+			e.buf.WriteByte(fieldBool)
+			if v {
+				e.buf.WriteByte(1)
+			} else {
+				e.buf.WriteByte(0)
+			}
+		default:
+			// Fields() only returns the types above, but encode anything else
+			// as a string rather than losing the field.
+			e.buf.WriteByte(fieldString)
+			e.putString(fmt.Sprint(v))
+		}
+	}
+	return e.buf.Bytes()
+}
+
+// decodeMetric decodes a record produced by encodeMetric.
+func decodeMetric(b []byte) (telegraf.Metric, error) {
+	d := &decoder{r: bytes.NewReader(b)}
+
+	flags := d.byte()
+	nsec := d.varint()
+	name := d.string()
+
+	tags := make(map[string]string)
+	for i := d.uvarint(); i > 0 && d.err == nil; i-- {
+		k := d.string()
+		tags[k] = d.string()
+	}
+
+	fields := make(map[string]interface{})
+	for i := d.uvarint(); i > 0 && d.err == nil; i-- {
+		k := d.string()
+		switch t := d.byte(); t {
+		case fieldFloat:
+			fields[k] = math.Float64frombits(d.uvarint())
+		case fieldInt:
+			fields[k] = d.varint()
+		case fieldUint:
+			fields[k] = d.uvarint()
+		case fieldString:
+			fields[k] = d.string()
+		case fieldBool:
+			fields[k] = d.byte() != 0
+		default:
+			if d.err == nil {
+				d.err = fmt.Errorf("unknown field type %q", t)
+			}
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	m, err := metric.New(name, tags, fields, time.Unix(0, nsec),
+		telegraf.ValueType(flags&^aggregateFlag))
+	if err != nil {
+		return nil, err
+	}
+	m.SetAggregate(flags&aggregateFlag != 0)
+	return m, nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) putUvarint(v uint64) {
+	n := binary.PutUvarint(e.tmp[:], v)
+	e.buf.Write(e.tmp[:n])
+}
+
+func (e *encoder) putVarint(v int64) {
+	n := binary.PutVarint(e.tmp[:], v)
+	e.buf.Write(e.tmp[:n])
+}
+
+func (e *encoder) putString(s string) {
+	e.putUvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// decoder reads values from a record, remembering the first error so that
+// callers only need to check once at the end.
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	b, err := d.r.ReadByte()
+	d.err = err
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	d.err = err
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	d.err = err
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(d.r.Len()) {
+		d.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	b := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, b)
+	return string(b)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -61,6 +62,21 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// digests maps every plugin to a digest of the table it was created
+	// from, see Reuse.
+	digests map[interface{}]string
+
+	// secretStores maps the id of every secret store to the store, and
+	// secrets caches the secrets already looked up by their reference.
+	secretStores map[string]telegraf.SecretStore
+	secrets      map[string]string
+
+	// Remote holds the options used to load configuration files from
+	// http(s) URLs.
+	Remote Remote
+	// remoteSums maps the URLs loaded to the SHA-256 sum of their contents.
+	remoteSums map[string][]byte
 }
 
 func NewConfig() *Config {
@@ -78,10 +94,20 @@ func NewConfig() *Config {
 		Processors:    make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+		digests:       make(map[interface{}]string),
+		secretStores:  make(map[string]telegraf.SecretStore),
+		secrets:       make(map[string]string),
+		remoteSums:    make(map[string][]byte),
 	}
 	return c
 }
 
+// RemoteSum returns the SHA-256 sum of the contents loaded from the http(s)
+// URL, or nil if it was not loaded.
+func (c *Config) RemoteSum(url string) []byte {
+	return c.remoteSums[url]
+}
+
 type AgentConfig struct {
 	// Interval at which to gather information
 	Interval internal.Duration
@@ -534,6 +560,33 @@ func (c *Config) LoadDirectory(path string) error {
 	return filepath.Walk(path, walkfn)
 }
 
+// CheckAggregatorOutputs returns an error if an aggregator routes its metrics
+// to an output that is not configured. It is to be called once the whole
+// configuration is loaded, as the outputs may be in other files than the
+// aggregators. Outputs left out by output filters are not known, so nothing is
+// checked when there are filters.
+func (c *Config) CheckAggregatorOutputs() error {
+	if len(c.OutputFilters) > 0 {
+		return nil
+	}
+	for _, agg := range c.Aggregators {
+		for _, name := range agg.Config.Outputs {
+			found := false
+			for _, o := range c.Outputs {
+				if o.Named(name) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("Undefined output %s requested by %s",
+					name, agg.Name())
+			}
+		}
+	}
+	return nil
+}
+
 // Try to find a default config file at these locations (in order):
 //   1. $TELEGRAF_CONFIG_PATH
 //   2. $HOME/.telegraf/telegraf.conf
@@ -558,7 +611,7 @@ func getDefaultConfigPath() (string, error) {
 		" in $TELEGRAF_CONFIG_PATH, %s, or %s", homefile, etcfile)
 }
 
-// LoadConfig loads the given config file and applies it to c
+// LoadConfig loads the given config file or http(s) URL and applies it to c
 func (c *Config) LoadConfig(path string) error {
 	var err error
 	if path == "" {
@@ -566,7 +619,7 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
-	tbl, err := parseFile(path)
+	tbl, err := c.parseFile(path)
 	if err != nil {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
 	}
@@ -597,6 +650,26 @@ func (c *Config) LoadConfig(path string) error {
 		}
 	}
 
+	// Parse secret stores before the plugins referencing them:
+	if val, ok := tbl.Fields["secretstores"]; ok {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("%s: invalid configuration", path)
+		}
+		for storeName, storeVal := range subTable.Fields {
+			storeTables, ok := storeVal.([]*ast.Table)
+			if !ok {
+				return fmt.Errorf("Unsupported config format: %s, file %s",
+					storeName, path)
+			}
+			for _, t := range storeTables {
+				if err = c.addSecretStore(storeName, t); err != nil {
+					return fmt.Errorf("Error parsing %s, %s", path, err)
+				}
+			}
+		}
+	}
+
 	// Parse all the rest of the plugins:
 	for name, val := range tbl.Fields {
 		subTable, ok := val.(*ast.Table)
@@ -605,7 +678,7 @@ func (c *Config) LoadConfig(path string) error {
 		}
 
 		switch name {
-		case "agent", "global_tags", "tags":
+		case "agent", "global_tags", "tags", "secretstores":
 		case "outputs":
 			for pluginName, pluginVal := range subTable.Fields {
 				switch pluginSubTable := pluginVal.(type) {
@@ -684,6 +757,7 @@ func (c *Config) LoadConfig(path string) error {
 	if len(c.Processors) > 1 {
 		sort.Sort(c.Processors)
 	}
+
 	return nil
 }
 
@@ -699,11 +773,21 @@ func escapeEnv(value string) string {
 	return envVarEscaper.Replace(value)
 }
 
-// parseFile loads a TOML configuration from a provided path and
-// returns the AST produced from the TOML parser. When loading the file, it
-// will find environment variables and replace them.
-func parseFile(fpath string) (*ast.Table, error) {
-	contents, err := ioutil.ReadFile(fpath)
+// parseFile loads a TOML configuration from a provided path or http(s) URL
+// and returns the AST produced from the TOML parser. When loading the file,
+// it will find environment variables and replace them.
+func (c *Config) parseFile(fpath string) (*ast.Table, error) {
+	var contents []byte
+	var err error
+	if IsURL(fpath) {
+		contents, err = c.Remote.Fetch(fpath)
+		if err == nil {
+			sum := sha256.Sum256(contents)
+			c.remoteSums[fpath] = sum[:]
+		}
+	} else {
+		contents, err = ioutil.ReadFile(fpath)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -728,6 +812,10 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested aggregator: %s", name)
 	}
 	aggregator := creator()
+	if err := c.resolveSecrets(table, secretOptions(aggregator)); err != nil {
+		return err
+	}
+	digest := tableDigest("aggregators."+name, table)
 
 	conf, err := buildAggregator(name, table)
 	if err != nil {
@@ -738,7 +826,14 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return err
 	}
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	ra := models.NewRunningAggregator(aggregator, conf)
+	ra.NewAggregator = func() (telegraf.Aggregator, error) {
+		aggregator := creator()
+		err := toml.UnmarshalTable(table, aggregator)
+		return aggregator, err
+	}
+	c.digests[ra] = digest
+	c.Aggregators = append(c.Aggregators, ra)
 	return nil
 }
 
@@ -748,6 +843,10 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested processor: %s", name)
 	}
 	processor := creator()
+	if err := c.resolveSecrets(table, secretOptions(processor)); err != nil {
+		return err
+	}
+	digest := tableDigest("processors."+name, table)
 
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
@@ -764,6 +863,7 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		Config:    processorConfig,
 	}
 
+	c.digests[rf] = digest
 	c.Processors = append(c.Processors, rf)
 	return nil
 }
@@ -777,6 +877,10 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	if err := c.resolveSecrets(table, secretOptions(output)); err != nil {
+		return err
+	}
+	digest := tableDigest("outputs."+name, table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -798,8 +902,20 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return err
 	}
 
+	batchSize := c.Agent.MetricBatchSize
+	if outputConfig.MetricBatchSize > 0 {
+		batchSize = outputConfig.MetricBatchSize
+	}
+	bufferLimit := c.Agent.MetricBufferLimit
+	if outputConfig.MetricBufferLimit > 0 {
+		bufferLimit = outputConfig.MetricBufferLimit
+	}
+
 	ro := models.NewRunningOutput(name, output, outputConfig,
-		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+		batchSize, bufferLimit)
+	// The buffer sizes come from the agent table, so they are part of the
+	// output's configuration as well.
+	c.digests[ro] = fmt.Sprintf("%s/%d/%d", digest, batchSize, bufferLimit)
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -818,6 +934,10 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	if err := c.resolveSecrets(table, secretOptions(input)); err != nil {
+		return err
+	}
+	digest := tableDigest("inputs."+name, table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -840,6 +960,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	c.digests[rp] = digest
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
@@ -857,9 +978,10 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 	}
 
 	conf := &models.AggregatorConfig{
-		Name:   name,
-		Delay:  time.Millisecond * 100,
-		Period: time.Second * 30,
+		Name:      name,
+		Delay:     time.Millisecond * 100,
+		Period:    time.Second * 30,
+		Windowing: models.WindowingClock,
 	}
 
 	if node, ok := tbl.Fields["period"]; ok {
@@ -888,6 +1010,33 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	if node, ok := tbl.Fields["windowing"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Windowing = str.Value
+			}
+		}
+	}
+	switch conf.Windowing {
+	case models.WindowingClock, models.WindowingTimestamp:
+	default:
+		return nil, fmt.Errorf("invalid windowing %q for aggregator %s, must be %q or %q",
+			conf.Windowing, name, models.WindowingClock, models.WindowingTimestamp)
+	}
+
+	if node, ok := tbl.Fields["grace"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				conf.Grace = dur
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["drop_original"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if b, ok := kv.Value.(*ast.Boolean); ok {
@@ -933,13 +1082,28 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	if node, ok := tbl.Fields["outputs"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						conf.Outputs = append(conf.Outputs, str.Value)
+					}
+				}
+			}
+		}
+	}
+
 	delete(tbl.Fields, "period")
 	delete(tbl.Fields, "delay")
+	delete(tbl.Fields, "windowing")
+	delete(tbl.Fields, "grace")
 	delete(tbl.Fields, "drop_original")
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "tags")
+	delete(tbl.Fields, "outputs")
 	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -952,7 +1116,10 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 // builds the filter and returns a
 // models.ProcessorConfig to be inserted into models.RunningProcessor
 func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error) {
-	conf := &models.ProcessorConfig{Name: name}
+	conf := &models.ProcessorConfig{
+		Name:      name,
+		Placement: models.PlacementAll,
+	}
 	unsupportedFields := []string{"tagexclude", "taginclude", "fielddrop", "fieldpass"}
 	for _, field := range unsupportedFields {
 		if _, ok := tbl.Fields[field]; ok {
@@ -973,7 +1140,23 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	if node, ok := tbl.Fields["placement"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Placement = str.Value
+			}
+		}
+	}
+	switch conf.Placement {
+	case models.PlacementAll, models.PlacementPreAggregation, models.PlacementPostAggregation:
+	default:
+		return nil, fmt.Errorf("invalid placement %q for processor %s, must be %q, %q or %q",
+			conf.Placement, name, models.PlacementAll,
+			models.PlacementPreAggregation, models.PlacementPostAggregation)
+	}
+
 	delete(tbl.Fields, "order")
+	delete(tbl.Fields, "placement")
 	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -1236,6 +1419,50 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["json_query"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONQuery = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_string_fields"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.JSONStringFields = append(c.JSONStringFields, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_name_key"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONNameKey = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_time_key"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONTimeKey = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_time_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONTimeFormat = str.Value
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["data_type"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1313,12 +1540,141 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["csv_header_row_count"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.CSVHeaderRowCount = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_skip_rows"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.CSVSkipRows = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_skip_columns"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.CSVSkipColumns = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_delimiter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVDelimiter = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_comment"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVComment = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_trim_space"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.CSVTrimSpace = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_column_names"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVColumnNames = append(c.CSVColumnNames, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_column_types"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVColumnTypes = append(c.CSVColumnTypes, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_tag_columns"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVTagColumns = append(c.CSVTagColumns, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_measurement_column"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVMeasurementColumn = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_timestamp_column"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVTimestampColumn = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVTimestampFormat = str.Value
+			}
+		}
+	}
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "separator")
 	delete(tbl.Fields, "templates")
 	delete(tbl.Fields, "tag_keys")
+	delete(tbl.Fields, "json_query")
+	delete(tbl.Fields, "json_string_fields")
+	delete(tbl.Fields, "json_name_key")
+	delete(tbl.Fields, "json_time_key")
+	delete(tbl.Fields, "json_time_format")
 	delete(tbl.Fields, "data_type")
 	delete(tbl.Fields, "collectd_auth_file")
 	delete(tbl.Fields, "collectd_security_level")
@@ -1328,6 +1684,18 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 	delete(tbl.Fields, "dropwizard_time_format")
 	delete(tbl.Fields, "dropwizard_tags_path")
 	delete(tbl.Fields, "dropwizard_tag_paths")
+	delete(tbl.Fields, "csv_header_row_count")
+	delete(tbl.Fields, "csv_skip_rows")
+	delete(tbl.Fields, "csv_skip_columns")
+	delete(tbl.Fields, "csv_delimiter")
+	delete(tbl.Fields, "csv_comment")
+	delete(tbl.Fields, "csv_trim_space")
+	delete(tbl.Fields, "csv_column_names")
+	delete(tbl.Fields, "csv_column_types")
+	delete(tbl.Fields, "csv_tag_columns")
+	delete(tbl.Fields, "csv_measurement_column")
+	delete(tbl.Fields, "csv_timestamp_column")
+	delete(tbl.Fields, "csv_timestamp_format")
 
 	return parsers.NewParser(c)
 }
@@ -1382,10 +1750,67 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["prometheus_export_timestamp"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.PrometheusExportTimestamp = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["prometheus_string_as_label"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.PrometheusStringAsLabel = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["carbon2_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.Carbon2Format = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["splunkmetric_hec_routing"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+				c.SplunkmetricHECRouting = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["splunkmetric_separator"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.SplunkmetricSeparator = str.Value
+			}
+		}
+	}
+
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "prefix")
 	delete(tbl.Fields, "template")
 	delete(tbl.Fields, "json_timestamp_units")
+	delete(tbl.Fields, "prometheus_export_timestamp")
+	delete(tbl.Fields, "prometheus_string_as_label")
+	delete(tbl.Fields, "carbon2_format")
+	delete(tbl.Fields, "splunkmetric_hec_routing")
+	delete(tbl.Fields, "splunkmetric_separator")
 	return serializers.NewSerializer(c)
 }
 
@@ -1409,5 +1834,223 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	if len(oc.Filter.FieldPass) > 0 {
 		oc.Filter.NamePass = oc.Filter.FieldPass
 	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Alias = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.FlushInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.FlushJitter = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_batch_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.MetricBatchSize = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_buffer_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.MetricBufferLimit = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_initial_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.Retry.InitialInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.Retry.MaxInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_multiplier"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch v := kv.Value.(type) {
+			case *ast.Float:
+				f, err := strconv.ParseFloat(v.Value, 64)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.Multiplier = f
+			case *ast.Integer:
+				f, err := strconv.ParseFloat(v.Value, 64)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.Multiplier = f
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_attempts"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.MaxAttempts = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_age"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.Retry.MaxAge = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_type"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferType = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_path"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.DiskBuffer.Directory = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_max_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				oc.DiskBuffer.MaxSize = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_segment_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				oc.DiskBuffer.SegmentSize = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_fsync"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.DiskBuffer.Fsync = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_fsync_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.DiskBuffer.FsyncInterval = dur
+			}
+		}
+	}
+
+	switch oc.BufferType {
+	case "", "memory":
+		oc.BufferType = "memory"
+	case "disk":
+		if oc.DiskBuffer.Directory == "" {
+			return nil, fmt.Errorf("buffer_path is required for the disk buffer of output %s", name)
+		}
+	default:
+		return nil, fmt.Errorf("Invalid buffer_type %q for output %s, must be \"memory\" or \"disk\"",
+			oc.BufferType, name)
+	}
+
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "flush_interval")
+	delete(tbl.Fields, "flush_jitter")
+	delete(tbl.Fields, "metric_batch_size")
+	delete(tbl.Fields, "metric_buffer_limit")
+	delete(tbl.Fields, "retry_initial_interval")
+	delete(tbl.Fields, "retry_max_interval")
+	delete(tbl.Fields, "retry_multiplier")
+	delete(tbl.Fields, "retry_max_attempts")
+	delete(tbl.Fields, "retry_max_age")
+	delete(tbl.Fields, "buffer_type")
+	delete(tbl.Fields, "buffer_path")
+	delete(tbl.Fields, "buffer_max_size")
+	delete(tbl.Fields, "buffer_segment_size")
+	delete(tbl.Fields, "buffer_fsync")
+	delete(tbl.Fields, "buffer_fsync_interval")
 	return oc, nil
 }
@@ -1,17 +1,22 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/directory"
 
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -174,3 +179,155 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_Reuse(t *testing.T) {
+	old := NewConfig()
+	assert.NoError(t, old.LoadConfig("./testdata/single_plugin.toml"))
+	assert.NoError(t, old.LoadConfig("./testdata/subconfig/exec.conf"))
+
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig("./testdata/single_plugin.toml"))
+	assert.NoError(t, c.LoadConfig("./testdata/subconfig/memcached.conf"))
+	c.Reuse(old)
+
+	// memcached is unchanged, exec was removed and a second memcached
+	// configured differently was added.
+	assert.Len(t, c.Inputs, 2)
+	assert.True(t, c.Inputs[0] == old.Inputs[0])
+	assert.True(t, c.Inputs[1] != old.Inputs[0])
+	assert.True(t, c.Inputs[1] != old.Inputs[1])
+}
+
+func TestTableDigest(t *testing.T) {
+	digest := func(s string) string {
+		tbl, err := toml.Parse([]byte(s))
+		assert.NoError(t, err)
+		return tableDigest("inputs.test", tbl)
+	}
+
+	a := digest("a = 1\nb = \"x\" # comment\n[tags]\n  c = \"y\"\n")
+	assert.Equal(t, a, digest("b = \"x\"\na = 1\n[tags]\n  c = \"y\"\n"))
+	assert.NotEqual(t, a, digest("a = 2\nb = \"x\"\n[tags]\n  c = \"y\"\n"))
+	assert.NotEqual(t, a, digest("a = 1\nb = \"x\"\n[tags]\n  c = \"z\"\n"))
+}
+
+func TestConfig_SecretStore(t *testing.T) {
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig("./testdata/secret_store.toml"))
+
+	assert.Len(t, c.Inputs, 1)
+	m := c.Inputs[0].Input.(*memcached.Memcached)
+	assert.Equal(t, []string{"localhost", "memcached.example.com:11211"}, m.Servers)
+}
+
+func TestConfig_SecretStoreUnknown(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/secret_store_unknown.toml")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "@{vault:memcached_server}")
+
+	// Secrets of plugins that are filtered out are never looked up.
+	c = NewConfig()
+	c.InputFilters = []string{"exec"}
+	assert.NoError(t, c.LoadConfig("./testdata/secret_store_unknown.toml"))
+}
+
+type secretOptionPlugin struct {
+	Password internal.Secret `toml:"password"`
+	APIToken internal.Secret
+	Username string
+}
+
+func TestConfig_SecretOption(t *testing.T) {
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig("./testdata/secret_store.toml"))
+
+	tbl, err := toml.Parse([]byte(`
+password = "@{local:memcached_server}"
+api_token = "token-@{local:memcached_server}"
+username = "@{local:memcached_server}"
+`))
+	assert.NoError(t, err)
+	p := &secretOptionPlugin{}
+	assert.NoError(t, c.resolveSecrets(tbl, secretOptions(p)))
+	assert.NoError(t, toml.UnmarshalTable(tbl, p))
+
+	// Secret options use the stores of a configuration only once it has
+	// been accepted.
+	_, err = p.Password.Get()
+	assert.Error(t, err)
+	c.UseSecretStores()
+	defer internal.SetSecretResolver(nil)
+
+	// Plain string options are resolved when loading, secret options only
+	// when they are used.
+	assert.Equal(t, "memcached.example.com", p.Username)
+	password, err := p.Password.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "memcached.example.com", password)
+	token, err := p.APIToken.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-memcached.example.com", token)
+	assert.NotContains(t, fmt.Sprintf("%+v", p.Password), "memcached")
+
+	// Unknown secrets of secret options are still reported when loading.
+	tbl, err = toml.Parse([]byte(`password = "@{vault:password}"`))
+	assert.NoError(t, err)
+	err = c.resolveSecrets(tbl, secretOptions(p))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "@{vault:password}")
+}
+
+func TestConfig_AggregatorRouting(t *testing.T) {
+	parse := func(s string) *ast.Table {
+		tbl, err := toml.Parse([]byte(s))
+		assert.NoError(t, err)
+		return tbl
+	}
+
+	ac, err := buildAggregator("minmax", parse(`
+drop_original = true
+outputs = ["longterm", "file"]
+`))
+	assert.NoError(t, err)
+	assert.True(t, ac.DropOriginal)
+	assert.Equal(t, []string{"longterm", "file"}, ac.Outputs)
+
+	oc, err := buildOutput("influxdb", parse(`alias = "longterm"`))
+	assert.NoError(t, err)
+	assert.Equal(t, "longterm", oc.Alias)
+
+	pc, err := buildProcessor("printer", parse(``))
+	assert.NoError(t, err)
+	assert.Equal(t, models.PlacementAll, pc.Placement)
+
+	tbl := parse(`placement = "post-aggregation"`)
+	pc, err = buildProcessor("printer", tbl)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PlacementPostAggregation, pc.Placement)
+	assert.Empty(t, tbl.Fields)
+
+	_, err = buildProcessor("printer", parse(`placement = "sometimes"`))
+	assert.Error(t, err)
+}
+
+func TestConfig_CheckAggregatorOutputs(t *testing.T) {
+	c := NewConfig()
+	c.Outputs = append(c.Outputs,
+		&models.RunningOutput{Config: &models.OutputConfig{Name: "influxdb"}},
+		&models.RunningOutput{Config: &models.OutputConfig{
+			Name: "influxdb", Alias: "longterm"}})
+	c.Aggregators = append(c.Aggregators, &models.RunningAggregator{
+		Config: &models.AggregatorConfig{
+			Name: "minmax", Outputs: []string{"longterm", "influxdb"}}})
+	assert.NoError(t, c.CheckAggregatorOutputs())
+
+	c.Aggregators[0].Config.Outputs = []string{"longterm", "shortterm"}
+	err := c.CheckAggregatorOutputs()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "shortterm")
+
+	// The filtered out outputs are not known.
+	c.OutputFilters = []string{"file"}
+	assert.NoError(t, c.CheckAggregatorOutputs())
+}
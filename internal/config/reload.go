@@ -0,0 +1,138 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/influxdata/telegraf/internal/models"
+
+	"github.com/influxdata/toml/ast"
+)
+
+// Reuse replaces every plugin of c that is configured exactly like a plugin
+// of old by the plugin of old, so that its state, such as the metrics
+// buffered by an output, carries over to c. Each plugin of old is reused at
+// most once.
+//
+// A plugin of c that is also in old after Reuse has not changed; all other
+// plugins of c are new, and the remaining plugins of old have been removed.
+func (c *Config) Reuse(old *Config) {
+	pool := make(map[string][]interface{})
+	for _, p := range old.plugins() {
+		digest := old.digests[p]
+		pool[digest] = append(pool[digest], p)
+	}
+	take := func(p interface{}) interface{} {
+		digest, ok := c.digests[p]
+		if !ok || len(pool[digest]) == 0 {
+			return nil
+		}
+		reused := pool[digest][0]
+		pool[digest] = pool[digest][1:]
+		c.digests[reused] = digest
+		delete(c.digests, p)
+		return reused
+	}
+
+	// The plugin kind is part of the digest, so a reused plugin is always of
+	// the same type as the one it replaces.
+	for i, p := range c.Inputs {
+		if r := take(p); r != nil {
+			c.Inputs[i] = r.(*models.RunningInput)
+		}
+	}
+	for i, p := range c.Outputs {
+		if r := take(p); r != nil {
+			c.Outputs[i] = r.(*models.RunningOutput)
+		}
+	}
+	for i, p := range c.Aggregators {
+		if r := take(p); r != nil {
+			c.Aggregators[i] = r.(*models.RunningAggregator)
+		}
+	}
+	for i, p := range c.Processors {
+		if r := take(p); r != nil {
+			c.Processors[i] = r.(*models.RunningProcessor)
+		}
+	}
+}
+
+// plugins returns all the plugins of c.
+func (c *Config) plugins() []interface{} {
+	var plugins []interface{}
+	for _, p := range c.Inputs {
+		plugins = append(plugins, p)
+	}
+	for _, p := range c.Outputs {
+		plugins = append(plugins, p)
+	}
+	for _, p := range c.Aggregators {
+		plugins = append(plugins, p)
+	}
+	for _, p := range c.Processors {
+		plugins = append(plugins, p)
+	}
+	return plugins
+}
+
+// tableDigest returns a digest of the plugin name and the contents of its
+// table. Two tables have the same digest when they set the same keys to the
+// same values, regardless of order and comments.
+func tableDigest(name string, tbl *ast.Table) string {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	writeTable(&buf, tbl)
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+func writeTable(buf *bytes.Buffer, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for k := range tbl.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := tbl.Fields[k].(type) {
+		case *ast.KeyValue:
+			fmt.Fprintf(buf, "%q=", k)
+			writeValue(buf, v.Value)
+			buf.WriteByte('\n')
+		case *ast.Table:
+			fmt.Fprintf(buf, "[%q]\n", k)
+			writeTable(buf, v)
+			buf.WriteString("[]\n")
+		case []*ast.Table:
+			for _, t := range v {
+				fmt.Fprintf(buf, "[[%q]]\n", k)
+				writeTable(buf, t)
+				buf.WriteString("[[]]\n")
+			}
+		}
+	}
+}
+
+// writeValue writes the value of strings rather than their source, so that a
+// plugin referencing a secret in an option that is not an internal.Secret
+// changes when the secret does.
+func writeValue(buf *bytes.Buffer, value ast.Value) {
+	switch v := value.(type) {
+	case *ast.String:
+		fmt.Fprintf(buf, "%q", v.Value)
+	case *ast.Array:
+		buf.WriteByte('[')
+		for _, elem := range v.Value {
+			writeValue(buf, elem)
+			buf.WriteByte(',')
+		}
+		buf.WriteByte(']')
+	default:
+		buf.WriteString(v.Source())
+	}
+}
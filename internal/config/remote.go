@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Remote holds the options used to load configuration files from http(s)
+// URLs.
+type Remote struct {
+	// Token is sent as a bearer token in the Authorization header if set.
+	Token string
+
+	SSLCA              string
+	SSLCert            string
+	SSLKey             string
+	InsecureSkipVerify bool
+
+	// Timeout of a request, defaults to 20 seconds.
+	Timeout time.Duration
+}
+
+// IsURL reports whether path is an http(s) URL rather than a local file.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "http://") ||
+		strings.HasPrefix(path, "https://")
+}
+
+// Fetch returns the contents of the configuration at url.
+func (r *Remote) Fetch(url string) ([]byte, error) {
+	tlsCfg, err := internal.GetTLSConfig(
+		r.SSLCert, r.SSLKey, r.SSLCA, r.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = 20 * time.Second
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: timeout,
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Telegraf")
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch config, received status %s",
+			resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Watch fetches the configuration at url every interval and sends on the
+// returned channel whenever its contents change, until done is closed. last
+// is the SHA-256 sum of the contents that were loaded, see Config.RemoteSum.
+// If it is nil the contents fetched first are the reference for the first
+// change. Fetch errors are logged, the configuration is considered unchanged
+// then.
+func (r *Remote) Watch(
+	url string,
+	last []byte,
+	interval time.Duration,
+	done <-chan struct{},
+) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			contents, err := r.Fetch(url)
+			if err != nil {
+				log.Printf("E! Unable to poll config %s: %s", url, err)
+			} else {
+				sum := sha256.Sum256(contents)
+				if last != nil && !bytes.Equal(sum[:], last) {
+					log.Printf("I! Config %s changed", url)
+					select {
+					case changed <- struct{}{}:
+					default:
+					}
+				}
+				last = sum[:]
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return changed
+}
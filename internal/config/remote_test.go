@@ -0,0 +1,110 @@
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/inputs/memcached"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type configServer struct {
+	sync.Mutex
+	config []byte
+}
+
+func (s *configServer) set(config []byte) {
+	s.Lock()
+	defer s.Unlock()
+	s.config = config
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	w.Write(s.config)
+}
+
+func TestConfig_LoadRemote(t *testing.T) {
+	config, err := ioutil.ReadFile("./testdata/single_plugin.toml")
+	require.NoError(t, err)
+	ts := httptest.NewServer(&configServer{config: config})
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote = Remote{Token: "secret"}
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+	require.Len(t, c.Inputs, 1)
+	m := c.Inputs[0].Input.(*memcached.Memcached)
+	assert.Equal(t, []string{"localhost"}, m.Servers)
+
+	c = NewConfig()
+	assert.Error(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+}
+
+func TestRemote_Watch(t *testing.T) {
+	s := &configServer{config: []byte("a")}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	r := &Remote{Token: "secret"}
+	changed := r.Watch(ts.URL, nil, 10*time.Millisecond, done)
+
+	select {
+	case <-changed:
+		t.Fatal("unchanged config reported as changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.set([]byte("b"))
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("changed config not detected")
+	}
+}
+
+func TestRemote_WatchLoaded(t *testing.T) {
+	config, err := ioutil.ReadFile("./testdata/single_plugin.toml")
+	require.NoError(t, err)
+	s := &configServer{config: config}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote = Remote{Token: "secret"}
+	url := ts.URL + "/telegraf.conf"
+	require.NoError(t, c.LoadConfig(url))
+	require.NotNil(t, c.RemoteSum(url))
+
+	// Changed after loading but before the first poll.
+	s.set([]byte("b"))
+	done := make(chan struct{})
+	defer close(done)
+	changed := c.Remote.Watch(url, c.RemoteSum(url), time.Hour, done)
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change since loading not detected")
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	assert.True(t, IsURL("http://localhost/telegraf.conf"))
+	assert.True(t, IsURL("https://localhost/telegraf.conf"))
+	assert.False(t, IsURL("/etc/telegraf/telegraf.conf"))
+	assert.False(t, IsURL("http.conf"))
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// secretRe matches references to secrets, @{id:key}, in string values.
+var secretRe = regexp.MustCompile(`@\{([\w-]+):([^{}]+)\}`)
+
+var secretType = reflect.TypeOf(internal.Secret{})
+
+// addSecretStore adds the secret store defined in table. Every store needs a
+// unique id by which its secrets are referenced.
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secret store: %s", name)
+	}
+	store := creator()
+
+	var id string
+	if node, ok := table.Fields["id"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				id = str.Value
+			}
+		}
+	}
+	delete(table.Fields, "id")
+	if id == "" {
+		return fmt.Errorf("secret store %s requires an id", name)
+	}
+	if _, ok := c.secretStores[id]; ok {
+		return fmt.Errorf("duplicate secret store id: %s", id)
+	}
+
+	if err := toml.UnmarshalTable(table, store); err != nil {
+		return err
+	}
+	c.secretStores[id] = store
+	return nil
+}
+
+// secretOptions returns the keys of the options of plugin that are of type
+// internal.Secret, matched the same way the TOML decoder does.
+func secretOptions(plugin interface{}) map[string]bool {
+	keys := make(map[string]bool)
+	rt := reflect.TypeOf(plugin)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return keys
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Type != secretType {
+			continue
+		}
+		if tag := strings.Split(field.Tag.Get("toml"), ",")[0]; tag != "" {
+			keys[tag] = true
+		} else {
+			keys[normalizeKey(field.Name)] = true
+		}
+	}
+	return keys
+}
+
+func normalizeKey(key string) string {
+	return strings.Replace(strings.ToLower(key), "_", "", -1)
+}
+
+// UseSecretStores makes the secret options of all plugins look up their
+// secrets in the secret stores of c, including the plugins taken over from a
+// previous configuration on reload. It is to be called once c has been
+// accepted, so that an invalid configuration never replaces the stores in
+// use.
+func (c *Config) UseSecretStores() {
+	internal.SetSecretResolver(c.lookupSecrets)
+}
+
+// resolveSecrets replaces the references to secrets in the string values of
+// table, including those of arrays and subtables, by the secrets. It is only
+// called for the plugins that are loaded, so secrets are not looked up for
+// plugins that are filtered out.
+//
+// The options of the plugin listed in secrets are internal.Secrets, which look
+// up their secrets when they are used. Their references are only checked here
+// and kept in the configuration, so that these secrets are never stored in the
+// plugin. All other options are resolved eagerly: their secrets are stored in
+// the plugin in plain text, as documented in CONFIGURATION.md.
+//
+// The AST is modified rather than the text of the configuration so that the
+// secrets are never part of parse errors, and errors name only the store and
+// key of a secret.
+func (c *Config) resolveSecrets(table *ast.Table, secrets map[string]bool) error {
+	for key, field := range table.Fields {
+		var err error
+		switch v := field.(type) {
+		case *ast.KeyValue:
+			if secrets[key] || secrets[normalizeKey(key)] {
+				if str, ok := v.Value.(*ast.String); ok {
+					_, err = c.lookupSecrets(str.Value)
+				}
+				break
+			}
+			err = c.resolveValue(v.Value)
+		case *ast.Table:
+			err = c.resolveSecrets(v, nil)
+		case []*ast.Table:
+			for _, t := range v {
+				if err = c.resolveSecrets(t, nil); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolveValue(value ast.Value) error {
+	switch v := value.(type) {
+	case *ast.String:
+		resolved, err := c.resolveString(v.Value)
+		if err != nil {
+			return err
+		}
+		v.Value = resolved
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := c.resolveValue(elem); err != nil {
+				return err
+			}
+		}
+	case *ast.Table:
+		return c.resolveSecrets(v, nil)
+	}
+	return nil
+}
+
+// resolveString replaces the references to secrets in s, caching the
+// secrets for the other plugins referencing them.
+func (c *Config) resolveString(s string) (string, error) {
+	return c.replaceSecrets(s, c.secrets)
+}
+
+// lookupSecrets replaces the references to secrets in s without caching the
+// secrets. It is used by internal.Secret, and must be safe for concurrent use
+// once the configuration is loaded.
+func (c *Config) lookupSecrets(s string) (string, error) {
+	return c.replaceSecrets(s, nil)
+}
+
+func (c *Config) replaceSecrets(s string, cache map[string]string) (string, error) {
+	if !strings.Contains(s, "@{") {
+		return s, nil
+	}
+
+	var err error
+	resolved := secretRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		match := secretRe.FindStringSubmatch(ref)
+		id, key := match[1], match[2]
+
+		if value, ok := cache[ref]; ok {
+			return value
+		}
+		store, ok := c.secretStores[id]
+		if !ok {
+			err = fmt.Errorf("unknown secret store %q referenced by %s", id, ref)
+			return ref
+		}
+		value, getErr := store.Get(key)
+		if getErr != nil {
+			err = fmt.Errorf("unable to get secret %s: %s", ref, getErr)
+			return ref
+		}
+		if cache != nil {
+			cache[ref] = value
+		}
+		return value
+	})
+	return resolved, err
+}
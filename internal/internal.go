@@ -240,3 +240,50 @@ func RandomSleep(max time.Duration, shutdown chan struct{}) {
 		return
 	}
 }
+
+// ParseTimestamp parses timestamp according to format, which is either one of
+// "unix", "unix_ms", "unix_us" and "unix_ns" for a number of seconds,
+// milliseconds, microseconds or nanoseconds since the epoch, or a Go time
+// layout. A timestamp of a unix format may be a number or a string holding
+// one. Layouts without a time zone are parsed as UTC.
+func ParseTimestamp(format string, timestamp interface{}) (time.Time, error) {
+	var unit time.Duration
+	switch format {
+	case "unix":
+		unit = time.Second
+	case "unix_ms":
+		unit = time.Millisecond
+	case "unix_us":
+		unit = time.Microsecond
+	case "unix_ns":
+		unit = time.Nanosecond
+	default:
+		str, ok := timestamp.(string)
+		if !ok {
+			return time.Time{}, fmt.Errorf("timestamp %v is not a string", timestamp)
+		}
+		return time.Parse(format, str)
+	}
+
+	var f float64
+	switch v := timestamp.(type) {
+	case int64:
+		return time.Unix(0, v*int64(unit)).UTC(), nil
+	case int:
+		return time.Unix(0, int64(v)*int64(unit)).UTC(), nil
+	case float64:
+		f = v
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(0, i*int64(unit)).UTC(), nil
+		}
+		var err error
+		f, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp %q is not a number", v)
+		}
+	default:
+		return time.Time{}, fmt.Errorf("timestamp %v is not a number", timestamp)
+	}
+	return time.Unix(0, int64(f*float64(unit))).UTC(), nil
+}
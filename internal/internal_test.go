@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"fmt"
 	"os/exec"
 	"testing"
 	"time"
@@ -162,3 +163,57 @@ func TestDuration(t *testing.T) {
 	d.UnmarshalTOML([]byte(`1.5`))
 	assert.Equal(t, time.Second, d.Duration)
 }
+
+func TestSecret(t *testing.T) {
+	var s Secret
+
+	s.UnmarshalTOML([]byte(`"pa$$\"word"`))
+	v, err := s.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, `pa$$"word`, v)
+
+	s = Secret{}
+	s.UnmarshalTOML([]byte(`'@{store:key}'`))
+	SetSecretResolver(func(ref string) (string, error) {
+		return "resolved " + ref, nil
+	})
+	defer SetSecretResolver(nil)
+	v, err = s.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "resolved @{store:key}", v)
+
+	printed := fmt.Sprintf("%v %+v %#v %s", s, s, s, s)
+	assert.NotContains(t, printed, "@{store:key}")
+	assert.NotContains(t, fmt.Sprintf("%+v", struct{ P Secret }{s}), "@{")
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Unix(1518640000, 0).UTC()
+	cases := []struct {
+		format    string
+		timestamp interface{}
+	}{
+		{"unix", int64(1518640000)},
+		{"unix", "1518640000"},
+		{"unix", float64(1518640000)},
+		{"unix_ms", "1518640000000"},
+		{"unix_us", int64(1518640000000000)},
+		{"unix_ns", "1518640000000000000"},
+		{time.RFC3339, "2018-02-14T20:26:40Z"},
+		{"2006-01-02 15:04:05", "2018-02-14 20:26:40"},
+	}
+	for _, c := range cases {
+		tm, err := ParseTimestamp(c.format, c.timestamp)
+		assert.NoError(t, err, c.format)
+		assert.Equal(t, expected, tm, c.format)
+	}
+
+	tm, err := ParseTimestamp("unix", "1518640000.5")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1518640000, 500000000).UTC(), tm)
+
+	_, err = ParseTimestamp("unix", "yesterday")
+	assert.Error(t, err)
+	_, err = ParseTimestamp(time.RFC3339, int64(1518640000))
+	assert.Error(t, err)
+}
@@ -1,20 +1,59 @@
 package models
 
 import (
+	"fmt"
+	"log"
+	"sort"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// WindowingClock aggregates the metrics received during each period of
+	// wall-clock time, dropping metrics with timestamps outside of it.
+	WindowingClock = "clock"
+	// WindowingTimestamp aggregates metrics into windows of one period based
+	// on their timestamps, keeping several windows open at the same time.
+	WindowingTimestamp = "timestamp"
 )
 
 type RunningAggregator struct {
 	a      telegraf.Aggregator
 	Config *AggregatorConfig
 
+	// NewAggregator returns a new instance of the aggregator plugin,
+	// configured like the one the RunningAggregator was created with. It is
+	// required for timestamp windowing, where each open window needs its own
+	// instance.
+	NewAggregator func() (telegraf.Aggregator, error)
+
+	MetricsTooLate selfstat.Stat
+
 	metrics chan telegraf.Metric
 
 	periodStart time.Time
 	periodEnd   time.Time
+
+	// windows holds the open windows by the UnixNano of their start,
+	// watermark the latest metric timestamp seen and pushed the latest end
+	// of a pushed window, for timestamp windowing. All are kept across calls
+	// to Run.
+	windows   map[int64]*window
+	watermark time.Time
+	pushed    time.Time
+	// idle holds aggregator instances not used by any window.
+	idle []telegraf.Aggregator
+}
+
+// window is a single aggregation period of timestamp windowing.
+type window struct {
+	start   time.Time
+	end     time.Time
+	updated time.Time
+	a       telegraf.Aggregator
 }
 
 func NewRunningAggregator(
@@ -25,6 +64,13 @@ func NewRunningAggregator(
 		a:       a,
 		Config:  conf,
 		metrics: make(chan telegraf.Metric, 100),
+		MetricsTooLate: selfstat.Register(
+			"aggregate",
+			"metrics_too_late",
+			map[string]string{"aggregator": conf.Name},
+		),
+		windows: make(map[int64]*window),
+		idle:    []telegraf.Aggregator{a},
 	}
 }
 
@@ -42,6 +88,17 @@ type AggregatorConfig struct {
 
 	Period time.Duration
 	Delay  time.Duration
+
+	// Windowing is either WindowingClock or WindowingTimestamp, Grace is
+	// the time a window stays open for late metrics with timestamp
+	// windowing.
+	Windowing string
+	Grace     time.Duration
+
+	// Outputs are the aliases or names of the outputs the metrics of the
+	// aggregator are written to, all outputs when empty. DropOriginal then
+	// only applies to these outputs.
+	Outputs []string
 }
 
 func (r *RunningAggregator) Name() string {
@@ -89,9 +146,11 @@ func (r *RunningAggregator) Add(in telegraf.Metric) bool {
 		t := in.Time()
 		if ok := r.Config.Filter.Apply(name, fields, tags); !ok {
 			// aggregator should not apply this metric
+			in.Drop()
 			return false
 		}
 
+		in.Drop()
 		in, _ = metric.New(name, tags, fields, t)
 	}
 
@@ -116,6 +175,11 @@ func (r *RunningAggregator) Run(
 	acc telegraf.Accumulator,
 	shutdown chan struct{},
 ) {
+	if r.Config.Windowing == WindowingTimestamp {
+		r.runWindows(acc, shutdown)
+		return
+	}
+
 	// The start of the period is truncated to the nearest second.
 	//
 	// Every metric then gets it's timestamp checked and is dropped if it
@@ -149,13 +213,21 @@ func (r *RunningAggregator) Run(
 			}
 			return
 		case m := <-r.metrics:
-			if m.Time().Before(r.periodStart) ||
-				m.Time().After(r.periodEnd.Add(truncation).Add(r.Config.Delay)) {
+			if m.Time().Before(r.periodStart) {
+				// the metric belongs to a period that has already been
+				// pushed.
+				r.MetricsTooLate.Incr(1)
+				m.Drop()
+				continue
+			}
+			if m.Time().After(r.periodEnd.Add(truncation).Add(r.Config.Delay)) {
 				// the metric is outside the current aggregation period, so
 				// skip it.
+				m.Drop()
 				continue
 			}
 			r.add(m)
+			m.Drop()
 		case <-periodT.C:
 			r.periodStart = r.periodEnd
 			r.periodEnd = r.periodStart.Add(r.Config.Period)
@@ -164,3 +236,171 @@ func (r *RunningAggregator) Run(
 		}
 	}
 }
+
+// runWindows runs the running aggregator with timestamp windowing. Each
+// metric is added to the window of its timestamp, which is opened if needed.
+//
+// A window is pushed once a metric with a timestamp past its end plus the
+// grace period has been seen, or once it has not received a metric for a
+// period plus the grace period of wall-clock time, so that the last windows
+// are pushed when metrics stop arriving. A metric for a window that has
+// already been pushed, or that ends before the end of a pushed window, is
+// dropped as too late.
+//
+// The aggregates of a window are timestamped with the start of the window.
+// Open windows are not pushed on shutdown, they are kept for the next call
+// to Run.
+func (r *RunningAggregator) runWindows(
+	acc telegraf.Accumulator,
+	shutdown chan struct{},
+) {
+	idleT := time.NewTicker(r.Config.Period)
+	defer idleT.Stop()
+
+	for {
+		select {
+		case <-shutdown:
+			if len(r.metrics) > 0 {
+				// wait until metrics are flushed before exiting
+				continue
+			}
+			return
+		case m := <-r.metrics:
+			r.addWindowed(m)
+			m.Drop()
+			r.pushWindows(acc, func(w *window) bool {
+				return !w.end.Add(r.Config.Grace).After(r.watermark)
+			})
+		case now := <-idleT.C:
+			idleSince := now.Add(-r.Config.Period - r.Config.Grace)
+			r.pushWindows(acc, func(w *window) bool {
+				return w.updated.Before(idleSince)
+			})
+		}
+	}
+}
+
+// addWindowed adds m to the window of its timestamp.
+func (r *RunningAggregator) addWindowed(m telegraf.Metric) {
+	start := m.Time().Truncate(r.Config.Period)
+	end := start.Add(r.Config.Period)
+	w, ok := r.windows[start.UnixNano()]
+	if (!ok && !end.After(r.pushed)) || !end.Add(r.Config.Grace).After(r.watermark) {
+		r.MetricsTooLate.Incr(1)
+		return
+	}
+	if m.Time().After(r.watermark) {
+		r.watermark = m.Time()
+	}
+
+	if !ok {
+		a, err := r.aggregator()
+		if err != nil {
+			log.Printf("E! [%s] failed to open window: %s", r.Name(), err)
+			return
+		}
+		w = &window{start: start, end: end, a: a}
+		r.windows[start.UnixNano()] = w
+	}
+	w.updated = time.Now()
+	w.a.Add(m)
+}
+
+// pushWindows pushes and closes the windows for which done returns true, in
+// the order of their start.
+func (r *RunningAggregator) pushWindows(
+	acc telegraf.Accumulator,
+	done func(w *window) bool,
+) {
+	var closed []*window
+	for _, w := range r.windows {
+		if done(w) {
+			closed = append(closed, w)
+		}
+	}
+	sort.Slice(closed, func(i, j int) bool {
+		return closed[i].start.Before(closed[j].start)
+	})
+
+	for _, w := range closed {
+		w.a.Push(&windowAccumulator{Accumulator: acc, t: w.start})
+		w.a.Reset()
+		delete(r.windows, w.start.UnixNano())
+		r.idle = append(r.idle, w.a)
+		if w.end.After(r.pushed) {
+			r.pushed = w.end
+		}
+	}
+}
+
+// aggregator returns an aggregator instance for a new window.
+func (r *RunningAggregator) aggregator() (telegraf.Aggregator, error) {
+	if n := len(r.idle); n > 0 {
+		a := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		return a, nil
+	}
+	if r.NewAggregator == nil {
+		return nil, fmt.Errorf("only one window can be open at a time")
+	}
+	return r.NewAggregator()
+}
+
+// windowAccumulator timestamps the metrics added without a time with the
+// start of a window.
+type windowAccumulator struct {
+	telegraf.Accumulator
+	t time.Time
+}
+
+func (w *windowAccumulator) time(t []time.Time) time.Time {
+	if len(t) > 0 {
+		return t[0]
+	}
+	return w.t
+}
+
+func (w *windowAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	w.Accumulator.AddFields(measurement, fields, tags, w.time(t))
+}
+
+func (w *windowAccumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	w.Accumulator.AddGauge(measurement, fields, tags, w.time(t))
+}
+
+func (w *windowAccumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	w.Accumulator.AddCounter(measurement, fields, tags, w.time(t))
+}
+
+func (w *windowAccumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	w.Accumulator.AddSummary(measurement, fields, tags, w.time(t))
+}
+
+func (w *windowAccumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	w.Accumulator.AddHistogram(measurement, fields, tags, w.time(t))
+}
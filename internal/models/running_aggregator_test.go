@@ -11,6 +11,7 @@ import (
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAdd(t *testing.T) {
@@ -230,6 +231,126 @@ func TestMakeMetricA(t *testing.T) {
 	)
 }
 
+func TestTimestampWindowing(t *testing.T) {
+	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:      "TestTimestampWindowing",
+		Period:    time.Second * 10,
+		Grace:     time.Second * 5,
+		Windowing: WindowingTimestamp,
+	})
+	ra.NewAggregator = func() (telegraf.Aggregator, error) {
+		return &TestAggregator{}, nil
+	}
+	ra.MetricsTooLate.Set(0)
+	acc := testutil.Accumulator{}
+	shutdown := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ra.Run(&acc, shutdown)
+	}()
+
+	add := func(value int, sec int64) {
+		m := ra.MakeMetric(
+			"RITest",
+			map[string]interface{}{"value": value},
+			map[string]string{},
+			telegraf.Untyped,
+			time.Unix(sec, 0),
+		)
+		ra.Add(m)
+	}
+
+	// Metrics of an hour ago are aggregated in two concurrently open windows.
+	base := time.Now().Add(-time.Hour).Truncate(time.Second * 10).Unix()
+	add(1, base+1)
+	add(10, base+11)
+	add(2, base+9)
+	// Within the grace period of the first window.
+	add(100, base+14)
+	add(3, base+2)
+	// Closes the first window.
+	add(1000, base+15)
+	// Too late for the first window.
+	add(4, base+3)
+	// Closes the second window.
+	add(10000, base+60)
+
+	acc.Wait(2)
+	close(shutdown)
+	wg.Wait()
+
+	acc.Lock()
+	defer acc.Unlock()
+	assert.Equal(t, int64(1), ra.MetricsTooLate.Get())
+	require.Len(t, acc.Metrics, 2)
+	assert.Equal(t, int64(6), acc.Metrics[0].Fields["sum"])
+	assert.Equal(t, time.Unix(base, 0).UnixNano(), acc.Metrics[0].Time.UnixNano())
+	assert.Equal(t, int64(1110), acc.Metrics[1].Fields["sum"])
+	assert.Equal(t, time.Unix(base+10, 0).UnixNano(), acc.Metrics[1].Time.UnixNano())
+
+	// The window of the last metric is kept open.
+	assert.Len(t, ra.windows, 1)
+}
+
+func TestTimestampWindowingIdle(t *testing.T) {
+	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:      "TestTimestampWindowingIdle",
+		Period:    time.Millisecond * 100,
+		Windowing: WindowingTimestamp,
+	})
+	ra.NewAggregator = func() (telegraf.Aggregator, error) {
+		return &TestAggregator{}, nil
+	}
+	ra.MetricsTooLate.Set(0)
+	acc := testutil.Accumulator{}
+	shutdown := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ra.Run(&acc, shutdown)
+	}()
+
+	add := func(value int, t time.Time) {
+		m := ra.MakeMetric(
+			"RITest",
+			map[string]interface{}{"value": value},
+			map[string]string{},
+			telegraf.Untyped,
+			t,
+		)
+		ra.Add(m)
+	}
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	add(1, base.Add(time.Millisecond*50))
+	// The window is pushed once it has been idle.
+	acc.Wait(1)
+	// Too late for the pushed window, although its end is after the
+	// watermark.
+	add(2, base.Add(time.Millisecond*60))
+	// Too late for a window ending before the pushed one.
+	add(4, base.Add(-time.Millisecond*50))
+	// Opens the next window.
+	add(10, base.Add(time.Millisecond*150))
+	acc.Wait(2)
+	close(shutdown)
+	wg.Wait()
+
+	acc.Lock()
+	defer acc.Unlock()
+	assert.Equal(t, int64(2), ra.MetricsTooLate.Get())
+	require.Len(t, acc.Metrics, 2)
+	assert.Equal(t, int64(1), acc.Metrics[0].Fields["sum"])
+	assert.Equal(t, base.UnixNano(), acc.Metrics[0].Time.UnixNano())
+	assert.Equal(t, int64(10), acc.Metrics[1].Fields["sum"])
+	assert.Equal(t, base.Add(time.Millisecond*100).UnixNano(), acc.Metrics[1].Time.UnixNano())
+}
+
 type TestAggregator struct {
 	sum int64
 }
@@ -1,13 +1,16 @@
 package models
 
 import (
+	"io"
 	"log"
+	"math"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/buffer"
-	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -32,9 +35,26 @@ type RunningOutput struct {
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
 	WriteTime       selfstat.Stat
+	Retries         selfstat.Stat
+	MetricsGivenUp  selfstat.Stat
 
-	metrics     *buffer.Buffer
-	failMetrics *buffer.Buffer
+	// Only registered when the output uses a disk buffer.
+	MetricsReplayed selfstat.Stat
+	BufferDiskSize  selfstat.Stat
+
+	// batchReady is signaled when a full batch is waiting to be written, once
+	// notifyBatch has been set by BatchReady. It is never reassigned, as an
+	// output taken over on reload is used by two agents for a while.
+	batchReady  chan struct{}
+	notifyBatch int32
+
+	metrics     metricBuffer
+	failMetrics metricBuffer
+
+	// failures is the number of consecutive failed writes, retryAt is the
+	// earliest time of the next write attempt after a failure.
+	failures int
+	retryAt  time.Time
 
 	// Guards against concurrent calls to the Output as described in #3009
 	sync.Mutex
@@ -61,6 +81,7 @@ func NewRunningOutput(
 		Config:            conf,
 		MetricBufferLimit: bufferLimit,
 		MetricBatchSize:   batchSize,
+		batchReady:        make(chan struct{}, 1),
 		MetricsWritten: selfstat.Register(
 			"write",
 			"metrics_written",
@@ -86,38 +107,86 @@ func NewRunningOutput(
 			"write_time_ns",
 			map[string]string{"output": name},
 		),
+		Retries: selfstat.Register(
+			"write",
+			"retries",
+			map[string]string{"output": name},
+		),
+		MetricsGivenUp: selfstat.Register(
+			"write",
+			"metrics_given_up",
+			map[string]string{"output": name},
+		),
 	}
 	ro.BufferLimit.Set(int64(ro.MetricBufferLimit))
 	return ro
 }
 
-// AddMetric adds a metric to the output. This function can also write cached
-// points if FlushBufferWhenFull is true.
+// Named returns true if name is the alias or the plugin name of the output.
+func (ro *RunningOutput) Named(name string) bool {
+	return ro.Config.Alias == name || ro.Config.Name == name
+}
+
+// BatchReady returns a channel that receives a value whenever a full batch is
+// waiting to be written. Once it has been called, AddMetric leaves the write
+// to the goroutine flushing this output instead of writing the batch itself.
+func (ro *RunningOutput) BatchReady() <-chan struct{} {
+	atomic.StoreInt32(&ro.notifyBatch, 1)
+	return ro.batchReady
+}
+
+// AddMetric adds a metric to the output. When a full batch of metrics is
+// available it is written, or BatchReady is signaled if it is used.
 func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	if m == nil {
 		return
 	}
 	// Filter any tagexclude/taginclude parameters before adding metric
 	if ro.Config.Filter.IsActive() {
-		// In order to filter out tags, we need to create a new metric, since
-		// metrics are immutable once created.
 		name := m.Name()
 		tags := m.Tags()
 		fields := m.Fields()
-		t := m.Time()
 		if ok := ro.Config.Filter.Apply(name, fields, tags); !ok {
 			ro.MetricsFiltered.Incr(1)
+			m.Drop()
 			return
 		}
-		// error is not possible if creating from another metric, so ignore.
-		m, _ = metric.New(name, tags, fields, t)
+		// The output owns this metric, so remove the filtered tags and fields
+		// in place rather than creating a new metric, which would lose
+		// delivery tracking.
+		for k := range m.Tags() {
+			if _, ok := tags[k]; !ok {
+				m.RemoveTag(k)
+			}
+		}
+		for k := range m.Fields() {
+			if _, ok := fields[k]; !ok {
+				m.RemoveField(k)
+			}
+		}
 	}
 
 	ro.metrics.Add(m)
-	if ro.metrics.Len() == ro.MetricBatchSize {
-		batch := ro.metrics.Batch(ro.MetricBatchSize)
+	if ro.metrics.Len() >= ro.MetricBatchSize {
+		batch, commit := peek(ro.metrics, ro.MetricBatchSize)
+		defer commit()
+		if atomic.LoadInt32(&ro.notifyBatch) == 1 {
+			// Queue the batch behind any earlier unwritten metrics so that
+			// order is preserved, and wake up the flushing goroutine.
+			ro.failMetrics.Add(batch...)
+			select {
+			case ro.batchReady <- struct{}{}:
+			default:
+			}
+			return
+		}
+		batch = ro.expire(batch)
+		if ro.backingOff() {
+			ro.failMetrics.Add(batch...)
+			return
+		}
 		err := ro.write(batch)
-		if err != nil {
+		if err != nil && !ro.giveUp(batch) {
 			ro.failMetrics.Add(batch...)
 		}
 	}
@@ -127,8 +196,22 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 func (ro *RunningOutput) Write() error {
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
 	ro.BufferSize.Set(int64(nFails + nMetrics))
+	if ro.BufferDiskSize != nil {
+		ro.BufferDiskSize.Set(diskSize(ro.metrics) + diskSize(ro.failMetrics))
+	}
 	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
 		ro.Name, nFails+nMetrics, ro.MetricBufferLimit)
+
+	if ro.backingOff() {
+		// Queue new metrics behind the failed ones until the next attempt.
+		batch, commit := peek(ro.metrics, ro.MetricBatchSize)
+		ro.failMetrics.Add(ro.expire(batch)...)
+		commit()
+		log.Printf("D! Output [%s] backing off after %d failed writes, next "+
+			"attempt at %s", ro.Name, ro.failures, ro.retryAt.Format(time.RFC3339))
+		return nil
+	}
+
 	var err error
 	if !ro.failMetrics.IsEmpty() {
 		// how many batches of failed writes we need to write.
@@ -141,24 +224,35 @@ func (ro *RunningOutput) Write() error {
 			if i == nBatches-1 {
 				batchSize = nFails % ro.MetricBatchSize
 			}
-			batch := ro.failMetrics.Batch(batchSize)
+			batch, commit := peek(ro.failMetrics, batchSize)
+			batch = ro.expire(batch)
 			// If we've already failed previous writes, don't bother trying to
 			// write to this output again. We are not exiting the loop just so
 			// that we can rotate the metrics to preserve order.
 			if err == nil {
 				err = ro.write(batch)
+				if err != nil && ro.giveUp(batch) {
+					commit()
+					continue
+				}
 			}
 			if err != nil {
 				ro.failMetrics.Add(batch...)
 			}
+			commit()
 		}
 	}
 
-	batch := ro.metrics.Batch(ro.MetricBatchSize)
+	batch, commit := peek(ro.metrics, ro.MetricBatchSize)
+	defer commit()
+	batch = ro.expire(batch)
 	// see comment above about not trying to write to an already failed output.
 	// if ro.failMetrics is empty then err will always be nil at this point.
 	if err == nil {
 		err = ro.write(batch)
+		if err != nil && ro.giveUp(batch) {
+			return err
+		}
 	}
 
 	if err != nil {
@@ -175,6 +269,9 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	}
 	ro.Lock()
 	defer ro.Unlock()
+	if ro.failures > 0 {
+		ro.Retries.Incr(1)
+	}
 	start := time.Now()
 	err := ro.Output.Write(metrics)
 	elapsed := time.Since(start)
@@ -183,12 +280,230 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 			ro.Name, nMetrics, elapsed)
 		ro.MetricsWritten.Incr(int64(nMetrics))
 		ro.WriteTime.Incr(elapsed.Nanoseconds())
+		ro.failures = 0
+		ro.retryAt = time.Time{}
+		for _, m := range metrics {
+			m.Accept()
+		}
+		return nil
+	}
+
+	ro.failures++
+	if delay := ro.Config.Retry.Delay(ro.failures); delay > 0 {
+		ro.retryAt = time.Now().Add(delay)
 	}
 	return err
 }
 
+// backingOff returns true if the output should not be written to yet
+// because of previous failures.
+func (ro *RunningOutput) backingOff() bool {
+	ro.Lock()
+	defer ro.Unlock()
+	return ro.failures > 0 && time.Now().Before(ro.retryAt)
+}
+
+// giveUp drops a batch that failed to be written when the maximum number of
+// attempts has been reached, and returns true if it did so.
+func (ro *RunningOutput) giveUp(batch []telegraf.Metric) bool {
+	ro.Lock()
+	defer ro.Unlock()
+	maxAttempts := ro.Config.Retry.MaxAttempts
+	if maxAttempts <= 0 || ro.failures < maxAttempts {
+		return false
+	}
+
+	log.Printf("W! Output [%s] dropping %d metrics after %d failed writes",
+		ro.Name, len(batch), ro.failures)
+	ro.MetricsGivenUp.Incr(int64(len(batch)))
+	for _, m := range batch {
+		m.Reject()
+	}
+	// The next batch gets its own attempts.
+	ro.failures = 0
+	ro.retryAt = time.Time{}
+	return true
+}
+
+// expire removes the metrics that are older than the maximum retry age from
+// batch.
+func (ro *RunningOutput) expire(batch []telegraf.Metric) []telegraf.Metric {
+	maxAge := ro.Config.Retry.MaxAge
+	if maxAge <= 0 {
+		return batch
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	n := 0
+	for _, m := range batch {
+		if m.Time().Before(cutoff) {
+			m.Reject()
+			continue
+		}
+		batch[n] = m
+		n++
+	}
+	if dropped := len(batch) - n; dropped > 0 {
+		log.Printf("W! Output [%s] dropping %d metrics older than %s",
+			ro.Name, dropped, maxAge)
+		ro.MetricsGivenUp.Incr(int64(dropped))
+	}
+	return batch[:n]
+}
+
+// UseDiskBuffer replaces the in-memory buffers of the output with buffers
+// stored in the directory given by conf. Metrics left on disk by a previous
+// run are written again on the next flush.
+func (ro *RunningOutput) UseDiskBuffer(conf buffer.DiskConfig) error {
+	// The size limit only applies to metrics waiting for a retry, the pending
+	// buffer never holds more than a single batch.
+	pendingConf := conf
+	pendingConf.Directory = filepath.Join(conf.Directory, "pending")
+	pendingConf.MaxSize = 0
+	pending, err := buffer.NewDiskBuffer(pendingConf)
+	if err != nil {
+		return err
+	}
+
+	failedConf := conf
+	failedConf.Directory = filepath.Join(conf.Directory, "failed")
+	failed, err := buffer.NewDiskBuffer(failedConf)
+	if err != nil {
+		pending.Close()
+		return err
+	}
+
+	ro.metrics = pending
+	ro.failMetrics = failed
+
+	ro.MetricsReplayed = selfstat.Register(
+		"write",
+		"metrics_replayed",
+		map[string]string{"output": ro.Name},
+	)
+	ro.BufferDiskSize = selfstat.Register(
+		"write",
+		"buffer_disk_size",
+		map[string]string{"output": ro.Name},
+	)
+
+	replayed := pending.Replayed() + failed.Replayed()
+	ro.MetricsReplayed.Incr(int64(replayed))
+	ro.BufferDiskSize.Set(pending.Size() + failed.Size())
+	if replayed > 0 {
+		log.Printf("I! Output [%s] replaying %d metrics from disk buffer %s",
+			ro.Name, replayed, conf.Directory)
+	}
+	return nil
+}
+
+// CloseBuffer releases the resources held by the buffers of the output. For
+// disk buffers this syncs all remaining metrics to disk.
+func (ro *RunningOutput) CloseBuffer() error {
+	var err error
+	for _, b := range []metricBuffer{ro.metrics, ro.failMetrics} {
+		if c, ok := b.(io.Closer); ok {
+			if cerr := c.Close(); cerr != nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// metricBuffer is implemented by both the in-memory and the disk buffer.
+type metricBuffer interface {
+	IsEmpty() bool
+	Len() int
+	Add(metrics ...telegraf.Metric)
+	Batch(batchSize int) []telegraf.Metric
+}
+
+// peek removes a batch of metrics from the buffer. The returned function must
+// be called once the batch has been written, dropped or added to another
+// buffer. Until then a disk buffer keeps the batch on disk, so that it is not
+// lost if the agent stops while the batch is being written.
+func peek(b metricBuffer, batchSize int) ([]telegraf.Metric, func()) {
+	if db, ok := b.(*buffer.DiskBuffer); ok {
+		return db.Peek(batchSize)
+	}
+	return b.Batch(batchSize), func() {}
+}
+
+func diskSize(b metricBuffer) int64 {
+	if db, ok := b.(*buffer.DiskBuffer); ok {
+		return db.Size()
+	}
+	return 0
+}
+
 // OutputConfig containing name and filter
 type OutputConfig struct {
 	Name   string
 	Filter Filter
+
+	// Alias names the output, so that aggregators can route their metrics
+	// to it when there are several outputs of the same plugin.
+	Alias string
+
+	// FlushInterval and FlushJitter override the agent settings when not 0.
+	FlushInterval time.Duration
+	FlushJitter   time.Duration
+
+	// MetricBatchSize and MetricBufferLimit override the agent settings when
+	// not 0.
+	MetricBatchSize   int
+	MetricBufferLimit int
+
+	Retry RetryConfig
+
+	// BufferType is either "memory" or "disk".
+	BufferType string
+	DiskBuffer buffer.DiskConfig
+}
+
+// RetryConfig controls how writes are retried after an output fails.
+type RetryConfig struct {
+	// InitialInterval is the time to wait after the first failure. With the
+	// default of 0 the write is retried on the next flush.
+	InitialInterval time.Duration
+	// MaxInterval caps the time between attempts, 0 means no cap.
+	MaxInterval time.Duration
+	// Multiplier is applied to the interval after each further failure.
+	Multiplier float64
+	// MaxAttempts is the number of attempts to write a batch before it is
+	// dropped, 0 means no limit.
+	MaxAttempts int
+	// MaxAge drops metrics with a timestamp older than MaxAge instead of
+	// retrying them, 0 means no limit.
+	MaxAge time.Duration
+}
+
+// Delay returns the time to wait before the next attempt after the given
+// number of consecutive failures.
+func (c RetryConfig) Delay(failures int) time.Duration {
+	if c.InitialInterval <= 0 || failures <= 0 {
+		return 0
+	}
+
+	multiplier := c.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	limit := float64(math.MaxInt64)
+	if c.MaxInterval > 0 {
+		limit = float64(c.MaxInterval)
+	}
+
+	delay := float64(c.InitialInterval)
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= multiplier
+	}
+	if delay >= limit {
+		if c.MaxInterval > 0 {
+			return c.MaxInterval
+		}
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
 }
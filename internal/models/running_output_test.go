@@ -2,10 +2,15 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -298,6 +303,60 @@ func TestRunningOutputMultiFlushWhenFull(t *testing.T) {
 	assert.Len(t, m.Metrics(), 8)
 }
 
+// Test that a full batch is left for the flushing goroutine when BatchReady
+// is set.
+func TestRunningOutputBatchReady(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	batchReady := ro.BatchReady()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	// nothing written, but the flusher was notified
+	assert.Len(t, m.Metrics(), 0)
+	assert.Len(t, batchReady, 1)
+
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	assert.Len(t, m.Metrics(), 0)
+
+	err := ro.Write()
+	assert.NoError(t, err)
+	assert.Equal(t, append(first5, next5...), m.Metrics())
+}
+
+// Test that an output can be handed over to the flushing goroutine of another
+// agent while metrics are still being added, as happens on reload.
+func TestRunningOutputBatchReadyHandover(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1, 12)
+	first := ro.BatchReady()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, metric := range first5 {
+			ro.AddMetric(metric)
+		}
+	}()
+	second := ro.BatchReady()
+	wg.Wait()
+
+	assert.True(t, first == second)
+	assert.Len(t, second, 1)
+}
+
 func TestRunningOutputWriteFail(t *testing.T) {
 	conf := &OutputConfig{
 		Filter: Filter{},
@@ -479,6 +538,247 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+// Verify that metrics in a disk buffer are written by the next instance of
+// the output, in order.
+func TestRunningOutputDiskBufferReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+	diskConf := buffer.DiskConfig{Directory: dir, Fsync: buffer.FsyncAlways}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 5, 1000)
+	require.NoError(t, ro.UseDiskBuffer(diskConf))
+	// The stats are shared by all outputs with the same name.
+	replayed := ro.MetricsReplayed.Get()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	ro.AddMetric(next5[0])
+	err = ro.Write()
+	require.Error(t, err)
+	assert.Len(t, m.Metrics(), 0)
+	require.NoError(t, ro.CloseBuffer())
+
+	// Restart the output with the same buffer directory.
+	m = &mockOutput{}
+	ro = NewRunningOutput("test", m, conf, 5, 1000)
+	require.NoError(t, ro.UseDiskBuffer(diskConf))
+	assert.Equal(t, replayed+6, ro.MetricsReplayed.Get())
+	assert.True(t, ro.BufferDiskSize.Get() > 0)
+
+	err = ro.Write()
+	require.NoError(t, err)
+	require.NoError(t, ro.CloseBuffer())
+
+	require.Len(t, m.Metrics(), 6)
+	expected := append(first5, next5[0])
+	for i := range expected {
+		assert.Equal(t, expected[i].String(), m.Metrics()[i].String())
+	}
+}
+
+// Verify that no writes are attempted while backing off after a failure.
+func TestRunningOutputRetryBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			InitialInterval: time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("retry_backoff", m, conf, 100, 1000)
+	retries := ro.Retries.Get()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	err := ro.Write()
+	require.Error(t, err)
+	assert.Equal(t, retries, ro.Retries.Get())
+
+	// Even though the output works again, it is not retried yet.
+	m.failWrite = false
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	err = ro.Write()
+	require.NoError(t, err)
+	assert.Len(t, m.Metrics(), 0)
+	assert.Equal(t, retries, ro.Retries.Get())
+
+	// Once the backoff is over everything is written in order.
+	ro.retryAt = time.Now()
+	err = ro.Write()
+	require.NoError(t, err)
+	assert.Equal(t, retries+1, ro.Retries.Get())
+	assert.Equal(t, append(first5, next5...), m.Metrics())
+}
+
+// Verify that a batch is dropped after the maximum number of attempts.
+func TestRunningOutputRetryMaxAttempts(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			MaxAttempts: 2,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("retry_max_attempts", m, conf, 100, 1000)
+	givenUp := ro.MetricsGivenUp.Get()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Error(t, ro.Write())
+	assert.Equal(t, givenUp+5, ro.MetricsGivenUp.Get())
+
+	m.failWrite = false
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Equal(t, next5, m.Metrics())
+}
+
+// Verify that metrics older than the maximum age are not retried.
+func TestRunningOutputRetryMaxAge(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			MaxAge: time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("retry_max_age", m, conf, 100, 1000)
+	givenUp := ro.MetricsGivenUp.Get()
+
+	old := testutil.TestMetric(101, "old")
+	old, _ = metric.New(old.Name(), old.Tags(), old.Fields(),
+		time.Now().Add(-2*time.Hour))
+	ro.AddMetric(old)
+	recent, _ := metric.New("new", old.Tags(), old.Fields(), time.Now())
+	ro.AddMetric(recent)
+	require.Error(t, ro.Write())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 1)
+	assert.Equal(t, "new", m.Metrics()[0].Name())
+	assert.Equal(t, givenUp+1, ro.MetricsGivenUp.Get())
+}
+
+// Verify that tracked metrics are accepted when written, dropped when
+// filtered and rejected when given up on.
+func TestRunningOutputTracking(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			NameDrop: []string{"metric1"},
+		},
+		Retry: RetryConfig{
+			MaxAttempts: 1,
+		},
+	}
+	require.NoError(t, conf.Filter.Compile())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("tracking", m, conf, 100, 1000)
+
+	var infos []telegraf.DeliveryInfo
+	notify := func(info telegraf.DeliveryInfo) {
+		infos = append(infos, info)
+	}
+
+	filtered, _ := metric.WithGroupTracking(
+		[]telegraf.Metric{testutil.TestMetric(101, "metric1")}, notify)
+	ro.AddMetric(filtered[0])
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Delivered())
+
+	written, _ := metric.WithGroupTracking(
+		[]telegraf.Metric{testutil.TestMetric(101, "metric2")}, notify)
+	ro.AddMetric(written[0])
+	require.NoError(t, ro.Write())
+	require.Len(t, infos, 2)
+	assert.True(t, infos[1].Delivered())
+
+	m.failWrite = true
+	failed, _ := metric.WithGroupTracking(
+		[]telegraf.Metric{testutil.TestMetric(101, "metric3")}, notify)
+	ro.AddMetric(failed[0])
+	require.Error(t, ro.Write())
+	require.Len(t, infos, 3)
+	assert.False(t, infos[2].Delivered())
+}
+
+// Verify that tracked metrics in a disk buffer are delivered once written by
+// the output, not once they are on disk.
+func TestRunningOutputDiskBufferTracking(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+	diskConf := buffer.DiskConfig{Directory: dir, Fsync: buffer.FsyncNever}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("disk_tracking", m, conf, 100, 1000)
+	require.NoError(t, ro.UseDiskBuffer(diskConf))
+	defer ro.CloseBuffer()
+
+	var infos []telegraf.DeliveryInfo
+	notify := func(info telegraf.DeliveryInfo) {
+		infos = append(infos, info)
+	}
+
+	tracked, _ := metric.WithGroupTracking(
+		[]telegraf.Metric{testutil.TestMetric(101, "metric1")}, notify)
+	ro.AddMetric(tracked[0])
+	require.Error(t, ro.Write())
+	assert.Empty(t, infos)
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 1)
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Delivered())
+}
+
+func TestRetryConfigDelay(t *testing.T) {
+	c := RetryConfig{
+		InitialInterval: time.Second,
+		MaxInterval:     10 * time.Second,
+		Multiplier:      2,
+	}
+	assert.Equal(t, time.Duration(0), c.Delay(0))
+	assert.Equal(t, time.Second, c.Delay(1))
+	assert.Equal(t, 2*time.Second, c.Delay(2))
+	assert.Equal(t, 8*time.Second, c.Delay(4))
+	assert.Equal(t, 10*time.Second, c.Delay(5))
+	assert.Equal(t, 10*time.Second, c.Delay(1000))
+
+	c.MaxInterval = 0
+	assert.True(t, c.Delay(1000) > 0)
+
+	assert.Equal(t, time.Duration(0), RetryConfig{}.Delay(3))
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -6,6 +6,18 @@ import (
 	"github.com/influxdata/telegraf"
 )
 
+const (
+	// PlacementAll applies a processor to the metrics of inputs, before
+	// they are aggregated, and again to the metrics of aggregators.
+	PlacementAll = "all"
+	// PlacementPreAggregation applies a processor to the metrics of inputs
+	// only, before they are aggregated or written to the outputs.
+	PlacementPreAggregation = "pre-aggregation"
+	// PlacementPostAggregation applies a processor to the metrics of
+	// aggregators only.
+	PlacementPostAggregation = "post-aggregation"
+)
+
 type RunningProcessor struct {
 	Name string
 
@@ -22,9 +34,10 @@ func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp
 
 // FilterConfig containing a name and filter
 type ProcessorConfig struct {
-	Name   string
-	Order  int64
-	Filter Filter
+	Name      string
+	Order     int64
+	Filter    Filter
+	Placement string
 }
 
 func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
@@ -34,6 +47,10 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 	ret := []telegraf.Metric{}
 
 	for _, metric := range in {
+		if !rp.placed(metric) {
+			ret = append(ret, metric)
+			continue
+		}
 		if rp.Config.Filter.IsActive() {
 			// check if the filter should be applied to this metric
 			if ok := rp.Config.Filter.Apply(metric.Name(), metric.Fields(), metric.Tags()); !ok {
@@ -44,8 +61,36 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 		}
 		// This metric should pass through the filter, so call the filter Apply
 		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		out := rp.Processor.Apply(metric)
+		if !contains(out, metric) {
+			// The processor consumed the metric, by dropping it or
+			// replacing it by other metrics, so it is done with.
+			metric.Drop()
+		}
+		ret = append(ret, out...)
 	}
 
 	return ret
 }
+
+// contains returns true if metric is one of metrics.
+func contains(metrics []telegraf.Metric, metric telegraf.Metric) bool {
+	for _, m := range metrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// placed returns true if the placement of the processor covers the metric.
+func (rp *RunningProcessor) placed(metric telegraf.Metric) bool {
+	switch rp.Config.Placement {
+	case PlacementPreAggregation:
+		return !metric.IsAggregate()
+	case PlacementPostAggregation:
+		return metric.IsAggregate()
+	default:
+		return true
+	}
+}
@@ -115,3 +115,25 @@ func TestRunningProcessor_DroppedMetric(t *testing.T) {
 	}
 	assert.Equal(t, expectedNames, actualNames)
 }
+
+func TestRunningProcessor_Placement(t *testing.T) {
+	aggregate := testutil.TestMetric(1, "foo")
+	aggregate.SetAggregate(true)
+	inmetrics := []telegraf.Metric{testutil.TestMetric(1, "foo"), aggregate}
+
+	rfp := NewTestRunningProcessor()
+	rfp.Config.Placement = PlacementPreAggregation
+	out := rfp.Apply(inmetrics...)
+	assert.Equal(t, "fuz", out[0].Name())
+	assert.Equal(t, "foo", out[1].Name())
+
+	rfp.Config.Placement = PlacementPostAggregation
+	out = rfp.Apply(inmetrics...)
+	assert.Equal(t, "foo", out[0].Name())
+	assert.Equal(t, "fuz", out[1].Name())
+
+	rfp.Config.Placement = PlacementAll
+	out = rfp.Apply(inmetrics...)
+	assert.Equal(t, "fuz", out[0].Name())
+	assert.Equal(t, "fuz", out[1].Name())
+}
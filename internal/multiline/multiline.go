@@ -0,0 +1,145 @@
+// Package multiline joins log events that span several lines, such as stack
+// traces, into single events.
+package multiline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+const (
+	// Previous is the MatchWhichLine of patterns that match lines belonging
+	// to the line before them.
+	Previous = "previous"
+	// Next is the MatchWhichLine of patterns that match lines belonging to
+	// the line after them.
+	Next = "next"
+
+	defaultTimeout = 5 * time.Second
+)
+
+// Config is the multiline configuration of a plugin.
+type Config struct {
+	// Pattern matches the lines that belong to a neighbouring line.
+	Pattern string
+	// MatchWhichLine is the neighbour the matching lines belong to, either
+	// Previous, the default, or Next.
+	MatchWhichLine string `toml:"match_which_line"`
+	// InvertMatch makes the lines that do not match Pattern belong to their
+	// neighbour instead.
+	InvertMatch bool
+	// Timeout is how long an incomplete event waits for more lines before it
+	// is sent anyway. Defaults to 5 seconds.
+	Timeout internal.Duration
+}
+
+// Multiline decides which lines belong to the same event.
+type Multiline struct {
+	re      *regexp.Regexp
+	next    bool
+	invert  bool
+	timeout time.Duration
+}
+
+// New checks and compiles config.
+func New(config Config) (*Multiline, error) {
+	if config.Pattern == "" {
+		return nil, fmt.Errorf("multiline: pattern is required")
+	}
+	re, err := regexp.Compile(config.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("multiline: invalid pattern: %s", err)
+	}
+
+	m := &Multiline{
+		re:      re,
+		invert:  config.InvertMatch,
+		timeout: config.Timeout.Duration,
+	}
+	switch config.MatchWhichLine {
+	case "", Previous:
+	case Next:
+		m.next = true
+	default:
+		return nil, fmt.Errorf("multiline: match_which_line must be %q or %q, not %q",
+			Previous, Next, config.MatchWhichLine)
+	}
+	if m.timeout <= 0 {
+		m.timeout = defaultTimeout
+	}
+	return m, nil
+}
+
+// continues reports whether line belongs to one of its neighbours.
+func (m *Multiline) continues(line string) bool {
+	return m.re.MatchString(line) != m.invert
+}
+
+// Joiner returns a new Joiner of the lines of one source.
+func (m *Multiline) Joiner() *Joiner {
+	timer := time.NewTimer(m.timeout)
+	timer.Stop()
+	return &Joiner{m: m, timer: timer}
+}
+
+// Joiner joins lines into events. It is not safe for concurrent use.
+type Joiner struct {
+	m     *Multiline
+	lines []string
+	timer *time.Timer
+}
+
+// Add adds the next line and returns the event it completes, if any.
+func (j *Joiner) Add(line string) (string, bool) {
+	var event string
+	var ok bool
+	if j.m.next {
+		j.lines = append(j.lines, line)
+		if !j.m.continues(line) {
+			event, ok = j.Flush()
+		}
+	} else {
+		if !j.m.continues(line) {
+			event, ok = j.Flush()
+		}
+		j.lines = append(j.lines, line)
+	}
+
+	if len(j.lines) > 0 {
+		j.restartTimer()
+	}
+	return event, ok
+}
+
+// Flush returns the event of the lines added so far, if any, even though it
+// may not be complete.
+func (j *Joiner) Flush() (string, bool) {
+	j.timer.Stop()
+	if len(j.lines) == 0 {
+		return "", false
+	}
+	event := strings.Join(j.lines, "\n")
+	j.lines = j.lines[:0]
+	return event, true
+}
+
+// Timeout returns a channel that receives when an incomplete event has
+// waited for more lines for the configured timeout. Flush should be called
+// then.
+func (j *Joiner) Timeout() <-chan time.Time {
+	return j.timer.C
+}
+
+func (j *Joiner) restartTimer() {
+	if !j.timer.Stop() {
+		select {
+		case <-j.timer.C:
+		default:
+		}
+	}
+	j.timer.Reset(j.m.timeout)
+}
@@ -0,0 +1,110 @@
+package multiline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func join(t *testing.T, config Config, lines ...string) []string {
+	m, err := New(config)
+	require.NoError(t, err)
+	j := m.Joiner()
+
+	var events []string
+	for _, line := range lines {
+		if event, ok := j.Add(line); ok {
+			events = append(events, event)
+		}
+	}
+	if event, ok := j.Flush(); ok {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestMatchPrevious(t *testing.T) {
+	events := join(t, Config{Pattern: `^\s`},
+		"Exception in thread \"main\" java.lang.NullPointerException",
+		"\tat com.example.Foo.bar(Foo.java:16)",
+		"\tat com.example.Main.main(Main.java:5)",
+		"next event",
+		"last event",
+	)
+	assert.Equal(t, []string{
+		"Exception in thread \"main\" java.lang.NullPointerException\n" +
+			"\tat com.example.Foo.bar(Foo.java:16)\n" +
+			"\tat com.example.Main.main(Main.java:5)",
+		"next event",
+		"last event",
+	}, events)
+}
+
+func TestMatchNext(t *testing.T) {
+	events := join(t, Config{Pattern: `\\$`, MatchWhichLine: Next},
+		`first \`,
+		`second \`,
+		"third",
+		"fourth",
+	)
+	assert.Equal(t, []string{"first \\\nsecond \\\nthird", "fourth"}, events)
+}
+
+func TestInvertMatch(t *testing.T) {
+	events := join(t, Config{Pattern: `^\d{4}-\d{2}-\d{2} `, InvertMatch: true},
+		"2018-02-14 12:00:00 ERROR Traceback (most recent call last):",
+		`  File "main.py", line 1, in <module>`,
+		"ZeroDivisionError: division by zero",
+		"2018-02-14 12:00:01 INFO done",
+	)
+	assert.Equal(t, []string{
+		"2018-02-14 12:00:00 ERROR Traceback (most recent call last):\n" +
+			"  File \"main.py\", line 1, in <module>\n" +
+			"ZeroDivisionError: division by zero",
+		"2018-02-14 12:00:01 INFO done",
+	}, events)
+}
+
+func TestEmptyLines(t *testing.T) {
+	events := join(t, Config{Pattern: `^$`}, "a", "", "b", "")
+	assert.Equal(t, []string{"a\n", "b\n"}, events)
+}
+
+func TestTimeout(t *testing.T) {
+	m, err := New(Config{
+		Pattern: `^\s`,
+		Timeout: internal.Duration{Duration: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+	j := m.Joiner()
+
+	_, ok := j.Add("first")
+	require.False(t, ok)
+	_, ok = j.Add(" continued")
+	require.False(t, ok)
+
+	select {
+	case <-j.Timeout():
+	case <-time.After(time.Second):
+		t.Fatal("timeout did not expire")
+	}
+	event, ok := j.Flush()
+	require.True(t, ok)
+	assert.Equal(t, "first\n continued", event)
+
+	_, ok = j.Flush()
+	assert.False(t, ok)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+	_, err = New(Config{Pattern: `(`})
+	assert.Error(t, err)
+	_, err = New(Config{Pattern: `^\s`, MatchWhichLine: "both"})
+	assert.Error(t, err)
+}
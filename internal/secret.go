@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const redacted = "<redacted>"
+
+var (
+	secretMu       sync.RWMutex
+	secretResolver func(string) (string, error)
+)
+
+// SetSecretResolver sets the function replacing the references to secrets,
+// @{id:key}, in the value of a Secret by the secrets.
+func SetSecretResolver(resolve func(string) (string, error)) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secretResolver = resolve
+}
+
+// Secret is a configuration value, such as a password, that may reference
+// secrets as @{id:key}. Only the configured value is kept, the secrets are
+// looked up every time Get is called. A Secret is always printed redacted, so
+// that neither the configured value nor the secrets end up in logs or errors
+// that print the configuration of a plugin.
+type Secret struct {
+	value string
+}
+
+// NewSecret returns a Secret with the given configured value.
+func NewSecret(value string) Secret {
+	return Secret{value: value}
+}
+
+// UnmarshalTOML parses the secret from the TOML config file
+func (s *Secret) UnmarshalTOML(b []byte) error {
+	str := string(b)
+	switch {
+	case strings.HasPrefix(str, `"""`), strings.HasPrefix(str, `'''`):
+		str = strings.TrimPrefix(str[3:len(str)-3], "\n")
+	case strings.HasPrefix(str, `'`):
+		str = str[1 : len(str)-1]
+	default:
+		var err error
+		if str, err = strconv.Unquote(str); err != nil {
+			return err
+		}
+	}
+	s.value = str
+	return nil
+}
+
+// Get returns the value of the secret.
+func (s Secret) Get() (string, error) {
+	if !strings.Contains(s.value, "@{") {
+		return s.value, nil
+	}
+
+	secretMu.RLock()
+	resolve := secretResolver
+	secretMu.RUnlock()
+	if resolve == nil {
+		return "", errors.New("no secret stores are configured")
+	}
+	return resolve(s.value)
+}
+
+// IsEmpty returns true if no value is configured.
+func (s Secret) IsEmpty() bool {
+	return s.value == ""
+}
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return redacted
+}
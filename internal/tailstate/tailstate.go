@@ -0,0 +1,161 @@
+// Package tailstate persists the read offsets of tailed files, so that they
+// can be resumed where they were left after a restart.
+package tailstate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// File is the state of one file: where reading it stopped, and which file it
+// was.
+type File struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// State holds the offsets of a set of files and saves them to a state file.
+// It is safe for concurrent use.
+type State struct {
+	path string
+
+	sync.Mutex
+	files map[string]File
+}
+
+// New returns an empty state to be saved at path.
+func New(path string) *State {
+	return &State{
+		path:  path,
+		files: make(map[string]File),
+	}
+}
+
+// Load reads the state saved at path. A missing state file results in an
+// empty state.
+func Load(path string) (*State, error) {
+	s := New(path)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.files); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Offset returns the offset at which to resume reading path. known is false
+// if there is no state for path. If path is not the file it was when its
+// offset was set, because it was rotated, or was truncated since, the offset
+// is 0: it is read from the beginning.
+func (s *State) Offset(path string) (offset int64, known bool) {
+	s.Lock()
+	state, ok := s.files[path]
+	s.Unlock()
+	if !ok {
+		return 0, false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, true
+	}
+	if inode(info) != state.Inode || info.Size() < state.Offset {
+		return 0, true
+	}
+	return state.Offset, true
+}
+
+// Set records offset as the position up to which path has been read, ino
+// being the inode of the file read, as returned by Inode when it was opened
+// or by the Tracker of its tailer.
+func (s *State) Set(path string, ino uint64, offset int64) {
+	s.Lock()
+	s.files[path] = File{Inode: ino, Offset: offset}
+	s.Unlock()
+}
+
+// Inode returns the inode of the file at path, or 0 if it cannot be
+// determined. It is to be taken when the file is opened for reading, as path
+// may refer to another file by the time its offset is set.
+func Inode(path string) uint64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return inode(info)
+}
+
+// Tracker follows the inode of the file a tailer has open, which changes when
+// the tailer reopens a rotated or truncated file. It is to be set as the
+// Logger of the tail.Config of the tailer, as the tail package reports that
+// it has reopened a file through its logger only. Everything else logged is
+// discarded.
+type Tracker struct {
+	ino uint64 // first, for atomic access on 32 bit platforms.
+	*log.Logger
+	path string
+}
+
+// NewTracker returns a tracker for the tailer of path.
+func NewTracker(path string) *Tracker {
+	return &Tracker{
+		Logger: log.New(ioutil.Discard, "", 0),
+		path:   path,
+	}
+}
+
+// Opened records the inode of the file at path, to be called when the tailer
+// has opened it.
+func (t *Tracker) Opened() {
+	atomic.StoreUint64(&t.ino, Inode(t.path))
+}
+
+// Inode returns the inode of the file the tailer has open.
+func (t *Tracker) Inode() uint64 {
+	return atomic.LoadUint64(&t.ino)
+}
+
+// Printf is called by the tailer to log, including once it has reopened the
+// file.
+func (t *Tracker) Printf(format string, v ...interface{}) {
+	if strings.HasPrefix(format, "Successfully reopened") {
+		t.Opened()
+	}
+}
+
+// Save writes the state to its state file. The file is replaced atomically,
+// so that it is never left half written.
+func (s *State) Save() error {
+	s.Lock()
+	b, err := json.Marshal(s.files)
+	s.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
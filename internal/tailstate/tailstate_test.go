@@ -0,0 +1,78 @@
+package tailstate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-tailstate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(log, []byte("line 1\nline 2\n"), 0644))
+
+	statePath := filepath.Join(dir, "state.json")
+	s, err := Load(statePath)
+	require.NoError(t, err)
+	_, known := s.Offset(log)
+	assert.False(t, known)
+
+	s.Set(log, Inode(log), 7)
+	require.NoError(t, s.Save())
+
+	s, err = Load(statePath)
+	require.NoError(t, err)
+	offset, known := s.Offset(log)
+	assert.True(t, known)
+	assert.Equal(t, int64(7), offset)
+}
+
+func TestStateTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-tailstate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(log, []byte("line 1\nline 2\n"), 0644))
+
+	s, err := Load(filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	s.Set(log, Inode(log), 14)
+
+	require.NoError(t, ioutil.WriteFile(log, []byte("new\n"), 0644))
+	offset, known := s.Offset(log)
+	assert.True(t, known)
+	assert.Zero(t, offset)
+}
+
+func TestStateRotated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-tailstate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(log, []byte("line 1\n"), 0644))
+
+	s, err := Load(filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	ino := Inode(log)
+
+	// Keep the old file around so that the new one gets another inode. The
+	// offset read from the old file is set after the rotation.
+	require.NoError(t, os.Rename(log, log+".1"))
+	require.NoError(t, ioutil.WriteFile(log, []byte("line 1\nline 2\n"), 0644))
+	s.Set(log, ino, 7)
+
+	offset, known := s.Offset(log)
+	assert.True(t, known)
+	if Inode(log) != 0 {
+		assert.Zero(t, offset)
+	}
+}
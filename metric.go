@@ -61,4 +61,15 @@ type Metric interface {
 	// aggregator things:
 	SetAggregate(bool)
 	IsAggregate() bool
+
+	// Accept marks the metric as written successfully by an output.
+	Accept()
+
+	// Reject marks the metric as not written by an output, for instance
+	// because it was dropped from a full buffer.
+	Reject()
+
+	// Drop marks the metric as processed without being written, for instance
+	// because it was filtered or consumed by an aggregator.
+	Drop()
 }
@@ -189,6 +189,13 @@ func (m *metric) IsAggregate() bool {
 	return m.aggregate
 }
 
+// Accept, Reject and Drop are no-ops for untracked metrics.
+func (m *metric) Accept() {}
+
+func (m *metric) Reject() {}
+
+func (m *metric) Drop() {}
+
 func (m *metric) Type() telegraf.ValueType {
 	return m.mType
 }
@@ -271,7 +278,7 @@ func (m *metric) Split(maxSize int) []telegraf.Metric {
 		if i >= len(m.fields) {
 			// hit the end of the field byte slice
 			if len(fields) > 0 {
-				out = append(out, copyWith(m.name, m.tags, fields, m.t))
+				out = append(out, m.copyWith(fields))
 			}
 			break
 		}
@@ -291,7 +298,7 @@ func (m *metric) Split(maxSize int) []telegraf.Metric {
 			// selected field anyways. This means that the given maxSize is too
 			// small for a single field to fit.
 			if len(fields) > 0 {
-				out = append(out, copyWith(m.name, m.tags, fields, m.t))
+				out = append(out, m.copyWith(fields))
 			}
 
 			fields = make([]byte, 0, maxSize)
@@ -447,56 +454,71 @@ func (m *metric) AddTag(key, value string) {
 }
 
 func (m *metric) HasTag(key string) bool {
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
-	if i == -1 {
-		return false
-	}
-	return true
+	return m.indexTag(key) != -1
 }
 
 func (m *metric) RemoveTag(key string) {
 	m.hashID = 0
 
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
+	i := m.indexTag(key)
 	if i == -1 {
 		return
 	}
 
-	tmp := m.tags[0 : i-1]
-	j := indexUnescapedByte(m.tags[i:], ',')
+	// i is the index of the comma preceding the tag.
+	tmp := m.tags[0:i]
+	j := indexUnescapedByte(m.tags[i+1:], ',')
 	if j != -1 {
-		tmp = append(tmp, m.tags[i+j:]...)
+		tmp = append(tmp, m.tags[i+1+j:]...)
 	}
 	m.tags = tmp
 	return
 }
 
-func (m *metric) AddField(key string, value interface{}) {
-	m.fields = append(m.fields, ',')
-	m.fields = appendField(m.fields, key, value)
+// indexTag returns the index of the comma preceding the tag key, or -1 if
+// there is no such tag. Every tag is preceded by a comma, so a key is never
+// matched by the end of a longer key.
+func (m *metric) indexTag(key string) int {
+	return bytes.Index(m.tags, []byte(","+escape(key, "tagkey")+"="))
 }
 
-func (m *metric) HasField(key string) bool {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
+// AddField adds the field key, replacing its value if the field exists.
+func (m *metric) AddField(key string, value interface{}) {
+	i := m.indexField(key)
 	if i == -1 {
-		return false
+		m.fields = append(m.fields, ',')
+		m.fields = appendField(m.fields, key, value)
+		return
+	}
+
+	// The field is replaced in place, the fields following it are kept.
+	var rest []byte
+	if j := indexFieldEnd(m.fields[i:]); j != -1 {
+		rest = append(rest, m.fields[i+j:]...)
 	}
-	return true
+	m.fields = append(appendField(m.fields[:i], key, value), rest...)
+}
+
+func (m *metric) HasField(key string) bool {
+	return m.indexField(key) != -1
 }
 
 func (m *metric) RemoveField(key string) error {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
+	i := m.indexField(key)
 	if i == -1 {
 		return nil
 	}
 
 	var tmp []byte
-	if i != 0 {
+	j := indexFieldEnd(m.fields[i:])
+	switch {
+	case i != 0 && j != -1:
+		tmp = append(m.fields[0:i-1], m.fields[i+j:]...)
+	case i != 0:
 		tmp = m.fields[0 : i-1]
-	}
-	j := indexUnescapedByte(m.fields[i:], ',')
-	if j != -1 {
-		tmp = append(tmp, m.fields[i+j:]...)
+	case j != -1:
+		// The first field is removed, along with the comma following it.
+		tmp = m.fields[j+1:]
 	}
 
 	if len(tmp) == 0 {
@@ -507,21 +529,63 @@ func (m *metric) RemoveField(key string) error {
 	return nil
 }
 
+// indexField returns the index of the field key, or -1 if there is no such
+// field. The fields are scanned one by one, so a key is never matched by the
+// end of a longer key or by the contents of a string value.
+func (m *metric) indexField(key string) int {
+	k := []byte(escape(key, "tagkey") + "=")
+	for i := 0; i < len(m.fields); {
+		if bytes.HasPrefix(m.fields[i:], k) {
+			return i
+		}
+		j := indexFieldEnd(m.fields[i:])
+		if j == -1 {
+			return -1
+		}
+		i += j + 1
+	}
+	return -1
+}
+
+// indexFieldEnd returns the index of the comma following the first field in
+// buf, or -1 if it is the last field. Commas within string values are
+// skipped.
+func indexFieldEnd(buf []byte) int {
+	i := indexUnescapedByte(buf, '=')
+	if i == -1 || i+1 >= len(buf) || buf[i+1] != '"' {
+		return indexUnescapedByte(buf, ',')
+	}
+	// end index of the string value, starting from the opening quote
+	j := indexUnescapedByteBackslashEscaping(buf[i+2:], '"')
+	if j == -1 {
+		return -1
+	}
+	k := i + 2 + j + 1
+	if k >= len(buf) {
+		return -1
+	}
+	return k
+}
+
 func (m *metric) Copy() telegraf.Metric {
-	return copyWith(m.name, m.tags, m.fields, m.t)
+	return m.copyWith(m.fields)
 }
 
-func copyWith(name, tags, fields, t []byte) telegraf.Metric {
+// copyWith returns a copy of m with the given fields, keeping its type and
+// whether it is an aggregate.
+func (m *metric) copyWith(fields []byte) telegraf.Metric {
 	out := metric{
-		name:   make([]byte, len(name)),
-		tags:   make([]byte, len(tags)),
-		fields: make([]byte, len(fields)),
-		t:      make([]byte, len(t)),
-	}
-	copy(out.name, name)
-	copy(out.tags, tags)
+		name:      make([]byte, len(m.name)),
+		tags:      make([]byte, len(m.tags)),
+		fields:    make([]byte, len(fields)),
+		t:         make([]byte, len(m.t)),
+		mType:     m.mType,
+		aggregate: m.aggregate,
+	}
+	copy(out.name, m.name)
+	copy(out.tags, m.tags)
 	copy(out.fields, fields)
-	copy(out.t, t)
+	copy(out.t, m.t)
 	return &out
 }
 
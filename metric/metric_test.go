@@ -102,6 +102,86 @@ func TestNewMetric_Tags(t *testing.T) {
 	assert.Equal(t, "cpu value=1 "+fmt.Sprint(now.UnixNano())+"\n", m.String())
 }
 
+func TestRemoveTagSuffixOfOtherKey(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{"uint": "43", "int": "42"},
+		map[string]interface{}{"value": float64(1)},
+		time.Unix(0, 0))
+	assert.NoError(t, err)
+
+	m.RemoveTag("int")
+	assert.False(t, m.HasTag("int"))
+	assert.Equal(t, map[string]string{"uint": "43"}, m.Tags())
+}
+
+func TestRemoveFieldSuffixOfOtherKey(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{},
+		map[string]interface{}{"used": int64(1)},
+		time.Unix(0, 0))
+	assert.NoError(t, err)
+	m.AddField("ratio_used", 0.5)
+	m.AddField("is_used", true)
+
+	assert.NoError(t, m.RemoveField("used"))
+	assert.False(t, m.HasField("used"))
+	assert.Equal(t,
+		map[string]interface{}{"ratio_used": 0.5, "is_used": true},
+		m.Fields())
+
+	assert.False(t, m.HasField("s_used"))
+}
+
+func TestRemoveFieldInStringValue(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{},
+		map[string]interface{}{"msg": `say "a,other=1",other=1`},
+		time.Unix(0, 0))
+	assert.NoError(t, err)
+	m.AddField("value", int64(1))
+
+	assert.False(t, m.HasField("other"))
+	assert.NoError(t, m.RemoveField("other"))
+	assert.True(t, m.HasField("value"))
+	assert.NoError(t, m.RemoveField("msg"))
+	assert.Equal(t, map[string]interface{}{"value": int64(1)}, m.Fields())
+}
+
+func TestAddFieldReplaces(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": int64(1)},
+		time.Unix(0, 0))
+	assert.NoError(t, err)
+
+	m.AddField("value", "one")
+	assert.Equal(t, map[string]interface{}{"value": "one"}, m.Fields())
+
+	m.AddField("idle", 0.5)
+	m.AddField("used", true)
+	m.AddField("idle", int64(2))
+	assert.Equal(t,
+		map[string]interface{}{"value": "one", "idle": int64(2), "used": true},
+		m.Fields())
+	assert.Equal(t, "cpu value=\"one\",idle=2i,used=true 0\n", m.String())
+}
+
+func TestAddFieldReplacesStringValue(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{},
+		map[string]interface{}{"msg": "a,other=1"},
+		time.Unix(0, 0))
+	assert.NoError(t, err)
+	m.AddField("value", int64(1))
+
+	m.AddField("msg", "b")
+	m.AddField("other", int64(2))
+	assert.Equal(t,
+		map[string]interface{}{"msg": "b", "value": int64(1), "other": int64(2)},
+		m.Fields())
+	assert.Equal(t, "cpu msg=\"b\",value=1i,other=2i 0\n", m.String())
+}
+
 func TestSerialize(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{
@@ -242,6 +322,7 @@ func TestNewMetric_FieldModifiers(t *testing.T) {
 	m.AddField("value2", int64(101))
 	assert.NoError(t, m.RemoveField("value"))
 	assert.False(t, m.HasField("value"))
+	assert.Equal(t, map[string]interface{}{"value2": int64(101)}, m.Fields())
 }
 
 func TestNewMetric_Fields(t *testing.T) {
@@ -310,6 +391,20 @@ func TestNewMetric_Copy(t *testing.T) {
 		m2.String())
 }
 
+func TestNewMetric_CopyAggregate(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{},
+		map[string]interface{}{"float": float64(1)},
+		time.Unix(0, 0),
+		telegraf.Counter)
+	assert.NoError(t, err)
+	m.SetAggregate(true)
+
+	m2 := m.Copy()
+	assert.True(t, m2.IsAggregate())
+	assert.Equal(t, telegraf.Counter, m2.Type())
+}
+
 func TestNewMetric_AllTypes(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{}
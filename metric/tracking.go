@@ -0,0 +1,130 @@
+package metric
+
+import (
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NotifyFunc is called when a tracked metric group has been fully processed.
+type NotifyFunc = func(track telegraf.DeliveryInfo)
+
+var lastTrackingID uint64
+
+func newTrackingID() telegraf.TrackingID {
+	return telegraf.TrackingID(atomic.AddUint64(&lastTrackingID, 1))
+}
+
+// trackingData is shared by all metrics of a group and their copies.
+type trackingData struct {
+	id       telegraf.TrackingID
+	rc       int32
+	rejected int32
+	notify   NotifyFunc
+}
+
+func (d *trackingData) incr() {
+	atomic.AddInt32(&d.rc, 1)
+}
+
+func (d *trackingData) decr() {
+	if atomic.AddInt32(&d.rc, -1) == 0 {
+		d.notify(&deliveryInfo{
+			id:        d.id,
+			delivered: atomic.LoadInt32(&d.rejected) == 0,
+		})
+	}
+}
+
+// trackingMetric wraps a metric so that the group it belongs to is notified
+// once the metric and all of its copies have been accepted, rejected or
+// dropped.
+type trackingMetric struct {
+	telegraf.Metric
+	d    *trackingData
+	done int32
+}
+
+// WithGroupTracking wraps the metrics of group for delivery tracking. notify
+// is called exactly once, when every metric of the group has been processed.
+// An empty group is reported as delivered immediately.
+func WithGroupTracking(
+	group []telegraf.Metric,
+	notify NotifyFunc,
+) ([]telegraf.Metric, telegraf.TrackingID) {
+	d := &trackingData{
+		id:     newTrackingID(),
+		rc:     int32(len(group)),
+		notify: notify,
+	}
+	if len(group) == 0 {
+		notify(&deliveryInfo{id: d.id, delivered: true})
+		return group, d.id
+	}
+
+	tracked := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		tracked = append(tracked, &trackingMetric{Metric: m, d: d})
+	}
+	return tracked, d.id
+}
+
+// IsTracked returns true if m is tracked for delivery, that is if accepting,
+// rejecting or dropping it has an effect.
+func IsTracked(m telegraf.Metric) bool {
+	_, ok := m.(*trackingMetric)
+	return ok
+}
+
+// Copy returns a copy of the metric that is tracked as part of the same
+// group.
+func (m *trackingMetric) Copy() telegraf.Metric {
+	m.d.incr()
+	return &trackingMetric{Metric: m.Metric.Copy(), d: m.d}
+}
+
+// Split returns metrics tracked as part of the same group.
+func (m *trackingMetric) Split(maxSize int) []telegraf.Metric {
+	split := m.Metric.Split(maxSize)
+	out := make([]telegraf.Metric, 0, len(split))
+	for _, sm := range split {
+		m.d.incr()
+		out = append(out, &trackingMetric{Metric: sm, d: m.d})
+	}
+	m.finish()
+	return out
+}
+
+func (m *trackingMetric) Accept() {
+	m.finish()
+}
+
+func (m *trackingMetric) Reject() {
+	atomic.StoreInt32(&m.d.rejected, 1)
+	m.finish()
+}
+
+func (m *trackingMetric) Drop() {
+	m.finish()
+}
+
+// finish releases the metric's reference on the group, only the first call
+// has an effect.
+func (m *trackingMetric) finish() {
+	if atomic.CompareAndSwapInt32(&m.done, 0, 1) {
+		m.d.decr()
+	}
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (r *deliveryInfo) ID() telegraf.TrackingID {
+	return r.id
+}
+
+func (r *deliveryInfo) Delivered() bool {
+	return r.delivered
+}
@@ -0,0 +1,111 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustMetric(name string) telegraf.Metric {
+	m, err := New(name,
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+type deliveries struct {
+	infos []telegraf.DeliveryInfo
+}
+
+func (d *deliveries) notify(info telegraf.DeliveryInfo) {
+	d.infos = append(d.infos, info)
+}
+
+func TestTrackingAccept(t *testing.T) {
+	d := &deliveries{}
+	group, id := WithGroupTracking(
+		[]telegraf.Metric{mustMetric("cpu"), mustMetric("mem")}, d.notify)
+	require.Len(t, group, 2)
+
+	group[0].Accept()
+	assert.Empty(t, d.infos)
+
+	// Calling Accept again on the same metric has no effect.
+	group[0].Accept()
+	assert.Empty(t, d.infos)
+
+	group[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingReject(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking(
+		[]telegraf.Metric{mustMetric("cpu"), mustMetric("mem")}, d.notify)
+
+	group[0].Reject()
+	group[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.False(t, d.infos[0].Delivered())
+}
+
+func TestTrackingDrop(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking([]telegraf.Metric{mustMetric("cpu")}, d.notify)
+
+	group[0].Drop()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingCopy(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking([]telegraf.Metric{mustMetric("cpu")}, d.notify)
+
+	c := group[0].Copy()
+	group[0].Accept()
+	assert.Empty(t, d.infos)
+
+	c.Reject()
+	require.Len(t, d.infos, 1)
+	assert.False(t, d.infos[0].Delivered())
+}
+
+func TestTrackingSplit(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking([]telegraf.Metric{mustMetric("cpu")}, d.notify)
+
+	split := group[0].Split(1000)
+	require.Len(t, split, 1)
+	assert.Empty(t, d.infos)
+
+	split[0].Accept()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingEmptyGroup(t *testing.T) {
+	d := &deliveries{}
+	_, id := WithGroupTracking(nil, d.notify)
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingUniqueID(t *testing.T) {
+	d := &deliveries{}
+	_, id1 := WithGroupTracking(nil, d.notify)
+	_, id2 := WithGroupTracking(nil, d.notify)
+	assert.NotEqual(t, id1, id2)
+}
@@ -11,40 +11,50 @@ import (
 // bucketTag is the tag, which contains right bucket border
 const bucketTag = "le"
 
+// bucketLeftTag is the tag, which contains left bucket border of non-cumulative buckets
+const bucketLeftTag = "gt"
+
 // bucketInf is the right bucket border for infinite values
 const bucketInf = "+Inf"
 
+// bucketNegInf is the left border of the first non-cumulative bucket
+const bucketNegInf = "-Inf"
+
 // HistogramAggregator is aggregator with histogram configs and particular histograms for defined metrics
 type HistogramAggregator struct {
-	Configs []config `toml:"config"`
+	Configs             []config `toml:"config"`
+	ResetBuckets        bool     `toml:"reset"`
+	Cumulative          bool     `toml:"cumulative"`
+	PrometheusHistogram bool     `toml:"prometheus_histogram"`
 
-	buckets bucketsByMetrics
-	cache   map[uint64]metricHistogramCollection
+	cache map[uint64]metricHistogramCollection
 }
 
-// config is the config, which contains name, field of metric and histogram buckets.
+// config is the config, which contains name, field of metric, tags and histogram buckets.
 type config struct {
-	Metric  string   `toml:"measurement_name"`
-	Fields  []string `toml:"fields"`
-	Buckets buckets  `toml:"buckets"`
+	Metric  string            `toml:"measurement_name"`
+	Fields  []string          `toml:"fields"`
+	Tags    map[string]string `toml:"tags"`
+	Buckets buckets           `toml:"buckets"`
 }
 
-// bucketsByMetrics contains the buckets grouped by metric and field name
-type bucketsByMetrics map[string]bucketsByFields
-
-// bucketsByFields contains the buckets grouped by field name
-type bucketsByFields map[string]buckets
-
 // buckets contains the right borders buckets
 type buckets []float64
 
 // metricHistogramCollection aggregates the histogram data
 type metricHistogramCollection struct {
-	histogramCollection map[string]counts
+	histogramCollection map[string]*fieldHistogram
 	name                string
 	tags                map[string]string
 }
 
+// fieldHistogram is the histogram of a field, with the buckets of the config it matched
+type fieldHistogram struct {
+	buckets buckets
+	counts  counts
+	sum     float64
+}
+
 // counts is the number of hits in the bucket
 type counts []int64
 
@@ -57,8 +67,9 @@ type groupedByCountFields struct {
 
 // NewHistogramAggregator creates new histogram aggregator
 func NewHistogramAggregator() telegraf.Aggregator {
-	h := &HistogramAggregator{}
-	h.buckets = make(bucketsByMetrics)
+	h := &HistogramAggregator{
+		Cumulative: true,
+	}
 	h.resetCache()
 
 	return h
@@ -72,6 +83,20 @@ var sampleConfig = `
   ## aggregator and will not get sent to the output plugins.
   drop_original = false
 
+  ## If true, the histogram will be reset on flush instead
+  ## of accumulating the results.
+  reset = false
+
+  ## Whether bucket values should be accumulated. If set to false,
+  ## "gt" tag will be added to each bucket metric.
+  cumulative = true
+
+  ## If true, each histogram is emitted as a single metric of histogram type
+  ## named after the measurement and the field, with the cumulative bucket
+  ## counts, the count and the sum of the values. This allows outputs such as
+  ## prometheus_client to export it as a histogram.
+  prometheus_histogram = false
+
   ## Example config that aggregates all fields of the metric.
   # [[aggregators.histogram.config]]
   #   ## The set of buckets.
@@ -87,6 +112,15 @@ var sampleConfig = `
   #   measurement_name = "diskio"
   #   ## The concrete fields of metric
   #   fields = ["io_time", "read_time", "write_time"]
+
+  ## Example config that uses other buckets for the metrics of some hosts.
+  ## When several configs match a metric, the last one is used.
+  # [[aggregators.histogram.config]]
+  #   buckets = [0.0, 50.0, 100.0, 500.0, 1000.0]
+  #   measurement_name = "diskio"
+  #   fields = ["io_time", "read_time", "write_time"]
+  #   ## The tags a metric must have for the config to apply
+  #   tags = {host = "db01"}
 `
 
 // SampleConfig returns sample of config
@@ -101,42 +135,40 @@ func (h *HistogramAggregator) Description() string {
 
 // Add adds new hit to the buckets
 func (h *HistogramAggregator) Add(in telegraf.Metric) {
-	bucketsByField := make(map[string][]float64)
-	for field := range in.Fields() {
-		buckets := h.getBuckets(in.Name(), field)
-		if buckets != nil {
-			bucketsByField[field] = buckets
-		}
-	}
-
-	if len(bucketsByField) == 0 {
-		return
-	}
-
 	id := in.HashID()
 	agr, ok := h.cache[id]
 	if !ok {
 		agr = metricHistogramCollection{
 			name:                in.Name(),
 			tags:                in.Tags(),
-			histogramCollection: make(map[string]counts),
+			histogramCollection: make(map[string]*fieldHistogram),
 		}
 	}
 
 	for field, value := range in.Fields() {
-		if buckets, ok := bucketsByField[field]; ok {
-			if agr.histogramCollection[field] == nil {
-				agr.histogramCollection[field] = make(counts, len(buckets)+1)
+		histogram, ok := agr.histogramCollection[field]
+		if !ok {
+			buckets := h.getBuckets(agr.name, agr.tags, field)
+			if buckets == nil {
+				continue
 			}
-
-			if value, ok := convert(value); ok {
-				index := sort.SearchFloat64s(buckets, value)
-				agr.histogramCollection[field][index]++
+			histogram = &fieldHistogram{
+				buckets: buckets,
+				counts:  make(counts, len(buckets)+1),
 			}
+			agr.histogramCollection[field] = histogram
+		}
+
+		if value, ok := convert(value); ok {
+			index := sort.SearchFloat64s(histogram.buckets, value)
+			histogram.counts[index]++
+			histogram.sum += value
 		}
 	}
 
-	h.cache[id] = agr
+	if len(agr.histogramCollection) > 0 {
+		h.cache[id] = agr
+	}
 }
 
 // Push returns histogram values for metrics
@@ -144,8 +176,12 @@ func (h *HistogramAggregator) Push(acc telegraf.Accumulator) {
 	metricsWithGroupedFields := []groupedByCountFields{}
 
 	for _, aggregate := range h.cache {
-		for field, counts := range aggregate.histogramCollection {
-			h.groupFieldsByBuckets(&metricsWithGroupedFields, aggregate.name, field, copyTags(aggregate.tags), counts)
+		for field, histogram := range aggregate.histogramCollection {
+			if h.PrometheusHistogram {
+				h.pushHistogram(acc, aggregate.name, field, aggregate.tags, histogram)
+				continue
+			}
+			h.groupFieldsByBuckets(&metricsWithGroupedFields, aggregate.name, field, copyTags(aggregate.tags), histogram)
 		}
 	}
 
@@ -160,22 +196,60 @@ func (h *HistogramAggregator) groupFieldsByBuckets(
 	name string,
 	field string,
 	tags map[string]string,
-	counts []int64,
+	histogram *fieldHistogram,
 ) {
+	counts := histogram.counts
 	count := int64(0)
-	for index, bucket := range h.getBuckets(name, field) {
-		count += counts[index]
+	left := bucketNegInf
+	for index, bucket := range histogram.buckets {
+		right := strconv.FormatFloat(bucket, 'f', -1, 64)
+		if h.Cumulative {
+			count += counts[index]
+		} else {
+			count = counts[index]
+			tags[bucketLeftTag] = left
+		}
 
-		tags[bucketTag] = strconv.FormatFloat(bucket, 'f', -1, 64)
+		tags[bucketTag] = right
 		h.groupField(metricsWithGroupedFields, name, field, count, copyTags(tags))
+		left = right
 	}
 
-	count += counts[len(counts)-1]
+	// the overflow bucket
+	if h.Cumulative {
+		count += counts[len(counts)-1]
+	} else {
+		count = counts[len(counts)-1]
+		tags[bucketLeftTag] = left
+	}
 	tags[bucketTag] = bucketInf
 
 	h.groupField(metricsWithGroupedFields, name, field, count, tags)
 }
 
+// pushHistogram adds the histogram of a field as a metric of histogram type, with a field for the cumulative count
+// of each bucket named after its right border, and the count and sum of the values. The +Inf bucket is given by the
+// count.
+func (h *HistogramAggregator) pushHistogram(
+	acc telegraf.Accumulator,
+	name string,
+	field string,
+	tags map[string]string,
+	histogram *fieldHistogram,
+) {
+	fields := make(map[string]interface{}, len(histogram.buckets)+2)
+	count := int64(0)
+	for index, bucket := range histogram.buckets {
+		count += histogram.counts[index]
+		fields[strconv.FormatFloat(bucket, 'f', -1, 64)] = count
+	}
+	count += histogram.counts[len(histogram.counts)-1]
+	fields["count"] = count
+	fields["sum"] = histogram.sum
+
+	acc.AddHistogram(name+"_"+field, fields, copyTags(tags))
+}
+
 // groupField groups field by count value
 func (h *HistogramAggregator) groupField(
 	metricsWithGroupedFields *[]groupedByCountFields,
@@ -201,36 +275,45 @@ func (h *HistogramAggregator) groupField(
 	)
 }
 
-// Reset does nothing, because we need to collect counts for a long time, otherwise if config parameter 'reset' has
-// small value, we will get a histogram with a small amount of the distribution.
-func (h *HistogramAggregator) Reset() {}
+// Reset does nothing by default, because we need to collect counts for a long time, otherwise if config parameter
+// 'period' has small value, we will get a histogram with a small amount of the distribution. If 'reset' is set, the
+// counts are cleared, so that each histogram covers a single period.
+func (h *HistogramAggregator) Reset() {
+	if h.ResetBuckets {
+		h.resetCache()
+	}
+}
 
 // resetCache resets cached counts(hits) in the buckets
 func (h *HistogramAggregator) resetCache() {
 	h.cache = make(map[uint64]metricHistogramCollection)
 }
 
-// getBuckets finds buckets and returns them
-func (h *HistogramAggregator) getBuckets(metric string, field string) []float64 {
-	if buckets, ok := h.buckets[metric][field]; ok {
-		return buckets
-	}
-
+// getBuckets finds the buckets of the last config matching the metric and returns them
+func (h *HistogramAggregator) getBuckets(metric string, tags map[string]string, field string) []float64 {
+	var buckets []float64
 	for _, config := range h.Configs {
 		if config.Metric == metric {
-			if !isBucketExists(field, config) {
+			if !isBucketExists(field, config) || !isTagsMatching(tags, config) {
 				continue
 			}
 
-			if _, ok := h.buckets[metric]; !ok {
-				h.buckets[metric] = make(bucketsByFields)
-			}
+			buckets = sortBuckets(config.Buckets)
+		}
+	}
+
+	return buckets
+}
 
-			h.buckets[metric][field] = sortBuckets(config.Buckets)
+// isTagsMatching checks if the metric has all the tags of the config
+func isTagsMatching(tags map[string]string, cfg config) bool {
+	for key, val := range cfg.Tags {
+		if tags[key] != val {
+			return false
 		}
 	}
 
-	return h.buckets[metric][field]
+	return true
 }
 
 // isBucketExists checks if buckets exists for the passed field
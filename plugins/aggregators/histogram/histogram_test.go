@@ -9,12 +9,12 @@ import (
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // NewTestHistogram creates new test histogram aggregation with specified config
 func NewTestHistogram(cfg []config) telegraf.Aggregator {
-	htm := &HistogramAggregator{Configs: cfg}
-	htm.buckets = make(bucketsByMetrics)
+	htm := &HistogramAggregator{Configs: cfg, Cumulative: true}
 	htm.resetCache()
 
 	return htm
@@ -152,6 +152,130 @@ func TestHistogramDifferentPeriodsAndAllFields(t *testing.T) {
 	assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"a_bucket": int64(2), "b_bucket": int64(1), "c_bucket": int64(1)}, bucketInf)
 }
 
+// TestHistogramReset tests that the counts are cleared on reset when configured
+func TestHistogramReset(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Buckets: []float64{0.0, 10.0, 20.0, 30.0, 40.0}})
+	histogram := &HistogramAggregator{Configs: cfg, Cumulative: true, ResetBuckets: true}
+	histogram.resetCache()
+
+	acc := &testutil.Accumulator{}
+	histogram.Add(firstMetric1)
+	histogram.Push(acc)
+	histogram.Reset()
+	assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"a_bucket": int64(1)}, bucketInf)
+
+	acc.ClearMetrics()
+	histogram.Add(firstMetric2)
+	histogram.Push(acc)
+	histogram.Reset()
+	assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"a_bucket": int64(1)}, bucketInf)
+
+	acc.ClearMetrics()
+	histogram.Push(acc)
+	assert.Empty(t, acc.Metrics)
+}
+
+// TestHistogramNonCumulative tests that each bucket only counts its own values when cumulative is false
+func TestHistogramNonCumulative(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Buckets: []float64{0.0, 10.0, 20.0}})
+	histogram := &HistogramAggregator{Configs: cfg}
+	histogram.resetCache()
+
+	acc := &testutil.Accumulator{}
+	histogram.Add(firstMetric1)
+	histogram.Add(firstMetric2)
+	histogram.Add(secondMetric)
+	histogram.Push(acc)
+
+	require.Len(t, acc.Metrics, 4)
+	expected := []struct {
+		gt, le string
+		count  int64
+	}{
+		{bucketNegInf, "0", 0},
+		{"0", "10", 0},
+		{"10", "20", 2},
+		{"20", bucketInf, 0},
+	}
+	for _, e := range expected {
+		assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"a_bucket": e.count}, e.le)
+		found := false
+		for _, m := range acc.Metrics {
+			if m.Tags[bucketTag] == e.le {
+				assert.Equal(t, e.gt, m.Tags[bucketLeftTag])
+				found = true
+			}
+		}
+		assert.True(t, found, e.le)
+	}
+}
+
+// TestHistogramTags tests that configs only apply to metrics with their tags
+func TestHistogramTags(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Buckets: []float64{10.0}})
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Buckets: []float64{20.0},
+		Tags: map[string]string{"tag_name": "other_value"}})
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"b"}, Buckets: []float64{30.0},
+		Tags: map[string]string{"tag_name": "tag_value"}})
+	histogram := NewTestHistogram(cfg)
+
+	acc := &testutil.Accumulator{}
+	histogram.Add(firstMetric1)
+	histogram.Push(acc)
+
+	require.Len(t, acc.Metrics, 3)
+	assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"a_bucket": int64(0)}, "10")
+	assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"b_bucket": int64(0)}, "30")
+	assertContainsTaggedField(t, acc, "first_metric_name", map[string]interface{}{"a_bucket": int64(1), "b_bucket": int64(1)}, bucketInf)
+}
+
+// histogramAccumulator counts the metrics added as histograms
+type histogramAccumulator struct {
+	*testutil.Accumulator
+	histograms int
+}
+
+func (a *histogramAccumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.histograms++
+	a.Accumulator.AddHistogram(measurement, fields, tags, timestamp...)
+}
+
+// TestHistogramPrometheus tests the output of histograms as metrics of histogram type
+func TestHistogramPrometheus(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Buckets: []float64{0.0, 15.5, 20.0}})
+	histogram := &HistogramAggregator{Configs: cfg, PrometheusHistogram: true}
+	histogram.resetCache()
+
+	acc := &histogramAccumulator{Accumulator: &testutil.Accumulator{}}
+	histogram.Add(firstMetric1)
+	histogram.Add(firstMetric2)
+	histogram.Add(secondMetric)
+	histogram.Push(acc)
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, 1, acc.histograms)
+	m := acc.Metrics[0]
+	assert.Equal(t, "first_metric_name_a", m.Measurement)
+	assert.Equal(t, map[string]string{"tag_name": "tag_value"}, m.Tags)
+	assert.InDelta(t, 31.2, m.Fields["sum"], 1e-9)
+	delete(m.Fields, "sum")
+	assert.Equal(t, map[string]interface{}{
+		"0":     int64(0),
+		"15.5":  int64(1),
+		"20":    int64(2),
+		"count": int64(2),
+	}, m.Fields)
+}
+
 // TestWrongBucketsOrder tests the calling panic with incorrect order of buckets
 func TestWrongBucketsOrder(t *testing.T) {
 	defer func() {
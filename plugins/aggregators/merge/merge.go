@@ -0,0 +1,90 @@
+package merge
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type Merge struct {
+	cache map[key]*aggregate
+	// order holds the keys of cache in the order they were first seen, so
+	// that metrics are pushed in the order they arrived.
+	order []key
+}
+
+func NewMerge() telegraf.Aggregator {
+	m := &Merge{}
+	m.Reset()
+	return m
+}
+
+// key identifies the series and timestamp of the metrics that are merged.
+type key struct {
+	id   uint64
+	time int64
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	time   time.Time
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = true
+`
+
+func (m *Merge) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Merge) Description() string {
+	return "Merge metrics with the same name, tags and timestamp into one metric."
+}
+
+func (m *Merge) Add(in telegraf.Metric) {
+	k := key{id: in.HashID(), time: in.UnixNano()}
+	a, ok := m.cache[k]
+	if !ok {
+		// hit an uncached metric, create caches for first time:
+		a = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]interface{}),
+			time:   in.Time(),
+		}
+		m.cache[k] = a
+		m.order = append(m.order, k)
+	}
+
+	// Fields of later metrics replace those of earlier ones.
+	for k, v := range in.Fields() {
+		a.fields[k] = v
+	}
+}
+
+func (m *Merge) Push(acc telegraf.Accumulator) {
+	for _, k := range m.order {
+		a := m.cache[k]
+		acc.AddFields(a.name, a.fields, a.tags, a.time)
+	}
+}
+
+func (m *Merge) Reset() {
+	m.cache = make(map[key]*aggregate)
+	m.order = nil
+}
+
+func init() {
+	aggregators.Add("merge", func() telegraf.Aggregator {
+		return NewMerge()
+	})
+}
@@ -0,0 +1,72 @@
+package merge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newMetric(
+	tags map[string]string,
+	fields map[string]interface{},
+	t time.Time,
+) telegraf.Metric {
+	m, _ := metric.New("cpu", tags, fields, t)
+	return m
+}
+
+func TestMerge(t *testing.T) {
+	acc := testutil.Accumulator{}
+	merge := NewMerge()
+
+	t1 := time.Unix(1520000000, 0)
+	t2 := time.Unix(1520000010, 0)
+	cpu0 := map[string]string{"cpu": "cpu0"}
+	cpu1 := map[string]string{"cpu": "cpu1"}
+
+	merge.Add(newMetric(cpu0, map[string]interface{}{"usage_idle": 42.0}, t1))
+	merge.Add(newMetric(cpu1, map[string]interface{}{"usage_idle": 10.0}, t1))
+	merge.Add(newMetric(cpu0, map[string]interface{}{"usage_user": 58.0}, t1))
+	merge.Add(newMetric(cpu0, map[string]interface{}{"usage_idle": 50.0}, t2))
+	merge.Add(newMetric(cpu1, map[string]interface{}{"usage_user": int64(90)}, t1))
+	merge.Push(&acc)
+
+	require.Len(t, acc.Metrics, 3)
+
+	assert.Equal(t, cpu0, acc.Metrics[0].Tags)
+	assert.Equal(t, map[string]interface{}{
+		"usage_idle": 42.0,
+		"usage_user": 58.0,
+	}, acc.Metrics[0].Fields)
+	assert.Equal(t, t1.UnixNano(), acc.Metrics[0].Time.UnixNano())
+
+	assert.Equal(t, cpu1, acc.Metrics[1].Tags)
+	assert.Equal(t, map[string]interface{}{
+		"usage_idle": 10.0,
+		"usage_user": int64(90),
+	}, acc.Metrics[1].Fields)
+	assert.Equal(t, t1.UnixNano(), acc.Metrics[1].Time.UnixNano())
+
+	assert.Equal(t, cpu0, acc.Metrics[2].Tags)
+	assert.Equal(t, map[string]interface{}{"usage_idle": 50.0}, acc.Metrics[2].Fields)
+	assert.Equal(t, t2.UnixNano(), acc.Metrics[2].Time.UnixNano())
+}
+
+func TestMergeReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	merge := NewMerge()
+
+	merge.Add(newMetric(nil, map[string]interface{}{"a": 1.0}, time.Unix(0, 0)))
+	merge.Reset()
+	merge.Add(newMetric(nil, map[string]interface{}{"b": 2.0}, time.Unix(0, 0)))
+	merge.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{"b": 2.0}, acc.Metrics[0].Fields)
+}
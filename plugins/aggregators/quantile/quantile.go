@@ -0,0 +1,227 @@
+package quantile
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/beorn7/perks/quantile"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const (
+	algorithmSketch = "sketch"
+	algorithmExact  = "exact"
+)
+
+type Quantile struct {
+	Quantiles []float64 `toml:"quantiles"`
+	Algorithm string    `toml:"algorithm"`
+	Error     float64   `toml:"error"`
+
+	cache     map[uint64]aggregate
+	quantiles []float64
+	newFunc   func() estimator
+}
+
+func NewQuantile() *Quantile {
+	q := &Quantile{
+		Quantiles: []float64{0.25, 0.5, 0.75},
+		Algorithm: algorithmSketch,
+		Error:     0.01,
+	}
+	q.Reset()
+	return q
+}
+
+type aggregate struct {
+	fields map[string]estimator
+	name   string
+	tags   map[string]string
+}
+
+// estimator computes the quantiles of a stream of values.
+type estimator interface {
+	Add(v float64)
+	Quantile(q float64) float64
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to output in the range [0,1]
+  # quantiles = [0.25, 0.5, 0.75]
+
+  ## Type of aggregation algorithm
+  ## Supported are:
+  ##  "sketch" -- streaming sketch with bounded memory, the quantiles are
+  ##              within the given rank error
+  ##  "exact"  -- keeps all values of the period and interpolates linearly
+  ##              between them, only suited for small sets of values
+  # algorithm = "sketch"
+
+  ## Allowed rank error of the sketch algorithm, as a fraction of the number
+  ## of values. Smaller values use more memory.
+  # error = 0.01
+`
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	q.configure()
+
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		// hit an uncached metric, create caches for first time:
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]estimator),
+		}
+		q.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		if fv, ok := convert(v); ok {
+			e, ok := a.fields[k]
+			if !ok {
+				e = q.newFunc()
+				a.fields[k] = e
+			}
+			e.Add(fv)
+		}
+	}
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	q.configure()
+
+	for _, aggregate := range q.cache {
+		fields := map[string]interface{}{}
+		for k, e := range aggregate.fields {
+			for _, p := range q.quantiles {
+				fields[k+suffix(p)] = e.Quantile(p)
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(aggregate.name, fields, aggregate.tags)
+		}
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+// configure checks the configuration on first use.
+func (q *Quantile) configure() {
+	if q.newFunc != nil {
+		return
+	}
+
+	q.quantiles = nil
+	targets := make(map[float64]float64)
+	for _, p := range q.Quantiles {
+		if p < 0 || p > 1 || math.IsNaN(p) {
+			log.Printf("W! Quantile %v is not within [0,1], ignoring", p)
+			continue
+		}
+		q.quantiles = append(q.quantiles, p)
+		targets[p] = q.Error
+	}
+
+	switch q.Algorithm {
+	case algorithmExact:
+		q.newFunc = func() estimator { return &exact{} }
+	default:
+		if q.Algorithm != algorithmSketch {
+			log.Printf("W! Unrecognized quantile algorithm '%s', using '%s'",
+				q.Algorithm, algorithmSketch)
+		}
+		q.newFunc = func() estimator {
+			return &sketch{stream: quantile.NewTargeted(targets)}
+		}
+	}
+}
+
+// suffix returns the field name suffix of quantile p, such as "_p50" for 0.5
+// or "_p99.9" for 0.999.
+func suffix(p float64) string {
+	// Round to avoid artifacts such as 0.29*100 = 28.999999999999996.
+	percent := math.Floor(p*100*1e6+0.5) / 1e6
+	return "_p" + strconv.FormatFloat(percent, 'f', -1, 64)
+}
+
+// sketch estimates quantiles with bounded memory using the targeted
+// quantiles algorithm of Cormode, Korn, Muthukrishnan and Srivastava.
+type sketch struct {
+	stream *quantile.Stream
+}
+
+func (s *sketch) Add(v float64) {
+	s.stream.Insert(v)
+}
+
+func (s *sketch) Quantile(q float64) float64 {
+	return s.stream.Query(q)
+}
+
+// exact keeps all values and computes quantiles by linear interpolation
+// between the closest ranks.
+type exact struct {
+	values []float64
+	sorted bool
+}
+
+func (e *exact) Add(v float64) {
+	e.values = append(e.values, v)
+	e.sorted = false
+}
+
+func (e *exact) Quantile(q float64) float64 {
+	if len(e.values) == 0 {
+		return math.NaN()
+	}
+	if !e.sorted {
+		sort.Float64s(e.values)
+		e.sorted = true
+	}
+
+	rank := q * float64(len(e.values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	frac := rank - float64(lower)
+	return e.values[lower] + frac*(e.values[upper]-e.values[lower])
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return NewQuantile()
+	})
+}
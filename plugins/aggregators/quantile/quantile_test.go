@@ -0,0 +1,112 @@
+package quantile
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func addValues(q *Quantile, values ...float64) {
+	for _, v := range values {
+		m, _ := metric.New("m1",
+			map[string]string{"foo": "bar"},
+			map[string]interface{}{
+				"a":        v,
+				"b":        int64(v),
+				"ignoreme": "string",
+			},
+			time.Now(),
+		)
+		q.Add(m)
+	}
+}
+
+func TestQuantileExact(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.Algorithm = "exact"
+	q.Quantiles = []float64{0, 0.5, 0.9, 1}
+
+	addValues(q, 4, 1, 3, 2, 5)
+	q.Push(&acc)
+
+	expectedFields := map[string]interface{}{
+		"a_p0":   float64(1),
+		"a_p50":  float64(3),
+		"a_p90":  float64(4.6),
+		"a_p100": float64(5),
+		"b_p0":   float64(1),
+		"b_p50":  float64(3),
+		"b_p90":  float64(4.6),
+		"b_p100": float64(5),
+	}
+	require.Len(t, acc.Metrics, 1)
+	for k, v := range expectedFields {
+		assert.InDelta(t, v, acc.Metrics[0].Fields[k], 1e-9, k)
+	}
+	assert.Len(t, acc.Metrics[0].Fields, len(expectedFields))
+	assert.Equal(t, map[string]string{"foo": "bar"}, acc.Metrics[0].Tags)
+}
+
+func TestQuantileSketch(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.Quantiles = []float64{0.5, 0.99, 0.999}
+
+	r := rand.New(rand.NewSource(42))
+	values := make([]float64, 10000)
+	for i, n := range r.Perm(len(values)) {
+		values[i] = float64(n + 1)
+	}
+	addValues(q, values...)
+	q.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	fields := acc.Metrics[0].Fields
+	// The rank error of the sketch is 1% of the 10000 values.
+	assert.InDelta(t, 5000, fields["a_p50"], 100)
+	assert.InDelta(t, 9900, fields["a_p99"], 100)
+	assert.InDelta(t, 9990, fields["a_p99.9"], 100)
+}
+
+func TestQuantileReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+
+	addValues(q, 1, 2, 3)
+	q.Reset()
+	q.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+
+	addValues(q, 7)
+	q.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, float64(7), acc.Metrics[0].Fields["a_p25"])
+	assert.Equal(t, float64(7), acc.Metrics[0].Fields["a_p75"])
+}
+
+func TestQuantileInvalidQuantiles(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.Quantiles = []float64{-1, 0.5, 2}
+
+	addValues(q, 1)
+	q.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"a_p50": float64(1),
+		"b_p50": float64(1),
+	}, acc.Metrics[0].Fields)
+}
+
+func TestSuffix(t *testing.T) {
+	assert.Equal(t, "_p29", suffix(0.29))
+	assert.Equal(t, "_p99.9", suffix(0.999))
+	assert.Equal(t, "_p5", suffix(0.05))
+}
@@ -0,0 +1,179 @@
+package rate
+
+import (
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type Rate struct {
+	Fields []string `toml:"fields"`
+
+	// cache holds the series by HashID. It is kept across periods, so that
+	// the rate of a period starts at the last value of the previous one.
+	cache       map[uint64]*series
+	fieldFilter filter.Filter
+	configured  bool
+}
+
+func NewRate() *Rate {
+	return &Rate{cache: make(map[uint64]*series)}
+}
+
+type series struct {
+	name   string
+	tags   map[string]string
+	fields map[string]*counter
+	// seen is true if the series received a metric during the period.
+	seen bool
+}
+
+// counter tracks a monotonic field. delta and elapsed are summed over the
+// consecutive values received during the period.
+type counter struct {
+	last     float64
+	lastTime time.Time
+	delta    float64
+	elapsed  time.Duration
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Fields of monotonic counters to compute the rate of, supports globs.
+  ## By default all numeric fields are used.
+  # fields = ["bytes_*", "packets_*"]
+`
+
+func (r *Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rate) Description() string {
+	return "Compute the per second rate and the delta of monotonic counters."
+}
+
+func (r *Rate) Add(in telegraf.Metric) {
+	r.configure()
+
+	id := in.HashID()
+	s, ok := r.cache[id]
+	if !ok {
+		// hit an uncached metric, create caches for first time:
+		s = &series{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*counter),
+		}
+		r.cache[id] = s
+	}
+	s.seen = true
+
+	t := in.Time()
+	for k, v := range in.Fields() {
+		if r.fieldFilter != nil && !r.fieldFilter.Match(k) {
+			continue
+		}
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+
+		c, ok := s.fields[k]
+		if !ok {
+			s.fields[k] = &counter{last: fv, lastTime: t}
+			continue
+		}
+		if !t.After(c.lastTime) {
+			// out of order or duplicate value
+			continue
+		}
+
+		d := fv - c.last
+		if d < 0 {
+			// the counter has been reset, assume it restarted from zero
+			d = fv
+		}
+		c.delta += d
+		c.elapsed += t.Sub(c.lastTime)
+		c.last = fv
+		c.lastTime = t
+	}
+}
+
+func (r *Rate) Push(acc telegraf.Accumulator) {
+	for _, s := range r.cache {
+		if !s.seen {
+			continue
+		}
+		fields := map[string]interface{}{}
+		for k, c := range s.fields {
+			if c.elapsed <= 0 {
+				continue
+			}
+			fields[k+"_delta"] = c.delta
+			fields[k+"_rate"] = c.delta / c.elapsed.Seconds()
+		}
+		if len(fields) > 0 {
+			acc.AddFields(s.name, fields, s.tags)
+		}
+	}
+}
+
+// Reset clears the deltas of the period, keeping the last value of each
+// counter. Series that did not receive a metric during the period are
+// removed.
+func (r *Rate) Reset() {
+	for id, s := range r.cache {
+		if !s.seen {
+			delete(r.cache, id)
+			continue
+		}
+		s.seen = false
+		for _, c := range s.fields {
+			c.delta = 0
+			c.elapsed = 0
+		}
+	}
+}
+
+// configure compiles the field filter on first use.
+func (r *Rate) configure() {
+	if r.configured {
+		return
+	}
+	r.configured = true
+
+	var err error
+	r.fieldFilter, err = filter.Compile(r.Fields)
+	if err != nil {
+		log.Printf("E! Invalid rate fields %v, using all fields: %s",
+			r.Fields, err)
+		r.fieldFilter = nil
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("rate", func() telegraf.Aggregator {
+		return NewRate()
+	})
+}
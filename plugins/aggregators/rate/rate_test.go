@@ -0,0 +1,104 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newMetric(fields map[string]interface{}, sec int64) telegraf.Metric {
+	m, _ := metric.New("net",
+		map[string]string{"interface": "eth0"},
+		fields,
+		time.Unix(sec, 0),
+	)
+	return m
+}
+
+func TestRate(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+
+	r.Add(newMetric(map[string]interface{}{
+		"bytes_recv": int64(1000),
+		"up":         true,
+	}, 0))
+	r.Add(newMetric(map[string]interface{}{"bytes_recv": int64(1500)}, 10))
+	r.Add(newMetric(map[string]interface{}{"bytes_recv": int64(3000)}, 20))
+	r.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]string{"interface": "eth0"}, acc.Metrics[0].Tags)
+	assert.Equal(t, map[string]interface{}{
+		"bytes_recv_delta": float64(2000),
+		"bytes_recv_rate":  float64(100),
+	}, acc.Metrics[0].Fields)
+}
+
+func TestRateCounterReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+
+	r.Add(newMetric(map[string]interface{}{"bytes_recv": 1000.0}, 0))
+	r.Add(newMetric(map[string]interface{}{"bytes_recv": 1200.0}, 10))
+	// restarted from zero
+	r.Add(newMetric(map[string]interface{}{"bytes_recv": 300.0}, 20))
+	r.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, float64(500), acc.Metrics[0].Fields["bytes_recv_delta"])
+	assert.Equal(t, float64(25), acc.Metrics[0].Fields["bytes_recv_rate"])
+}
+
+func TestRateAcrossPeriods(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+
+	// A single value only sets the starting point.
+	r.Add(newMetric(map[string]interface{}{"bytes_recv": int64(100)}, 0))
+	r.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+	r.Reset()
+
+	r.Add(newMetric(map[string]interface{}{"bytes_recv": int64(400)}, 30))
+	r.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, float64(300), acc.Metrics[0].Fields["bytes_recv_delta"])
+	assert.Equal(t, float64(10), acc.Metrics[0].Fields["bytes_recv_rate"])
+	r.Reset()
+
+	// Series without metrics in a period are not pushed and are removed.
+	acc.ClearMetrics()
+	r.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+	r.Reset()
+	assert.Empty(t, r.cache)
+}
+
+func TestRateFields(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+	r.Fields = []string{"bytes_*"}
+
+	r.Add(newMetric(map[string]interface{}{
+		"bytes_recv": int64(0),
+		"drop_in":    int64(0),
+	}, 0))
+	r.Add(newMetric(map[string]interface{}{
+		"bytes_recv": int64(10),
+		"drop_in":    int64(10),
+	}, 1))
+	r.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"bytes_recv_delta": float64(10),
+		"bytes_recv_rate":  float64(10),
+	}, acc.Metrics[0].Fields)
+}
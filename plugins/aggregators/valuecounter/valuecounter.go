@@ -0,0 +1,90 @@
+package valuecounter
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type ValueCounter struct {
+	Fields []string `toml:"fields"`
+
+	cache map[uint64]aggregate
+}
+
+func NewValueCounter() *ValueCounter {
+	vc := &ValueCounter{}
+	vc.Reset()
+	return vc
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	counts map[string]int64
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## The fields for which the values will be counted
+  fields = []
+`
+
+func (vc *ValueCounter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (vc *ValueCounter) Description() string {
+	return "Count the occurrence of values in fields."
+}
+
+func (vc *ValueCounter) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := vc.cache[id]
+	if !ok {
+		// hit an uncached metric, create caches for first time:
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			counts: make(map[string]int64),
+		}
+		vc.cache[id] = a
+	}
+
+	fields := in.Fields()
+	for _, k := range vc.Fields {
+		if v, ok := fields[k]; ok {
+			a.counts[fmt.Sprintf("%s_%v", k, v)]++
+		}
+	}
+}
+
+func (vc *ValueCounter) Push(acc telegraf.Accumulator) {
+	for _, a := range vc.cache {
+		if len(a.counts) == 0 {
+			continue
+		}
+		fields := map[string]interface{}{}
+		for k, count := range a.counts {
+			fields[k] = count
+		}
+		acc.AddFields(a.name, fields, a.tags)
+	}
+}
+
+func (vc *ValueCounter) Reset() {
+	vc.cache = make(map[uint64]aggregate)
+}
+
+func init() {
+	aggregators.Add("valuecounter", func() telegraf.Aggregator {
+		return NewValueCounter()
+	})
+}
@@ -0,0 +1,64 @@
+package valuecounter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("http_response",
+		map[string]string{"server": "http://example.org"},
+		fields,
+		time.Now(),
+	)
+	return m
+}
+
+func TestValueCounter(t *testing.T) {
+	acc := testutil.Accumulator{}
+	vc := NewValueCounter()
+	vc.Fields = []string{"status", "http_response_code"}
+
+	vc.Add(newMetric(map[string]interface{}{
+		"status":             "OK",
+		"http_response_code": int64(200),
+		"response_time":      0.1,
+	}))
+	vc.Add(newMetric(map[string]interface{}{
+		"status":             "OK",
+		"http_response_code": int64(200),
+	}))
+	vc.Add(newMetric(map[string]interface{}{
+		"status":             "ERROR",
+		"http_response_code": int64(503),
+	}))
+	vc.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"status_OK":              int64(2),
+		"status_ERROR":           int64(1),
+		"http_response_code_200": int64(2),
+		"http_response_code_503": int64(1),
+	}, acc.Metrics[0].Fields)
+}
+
+func TestValueCounterReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	vc := NewValueCounter()
+	vc.Fields = []string{"status"}
+
+	vc.Add(newMetric(map[string]interface{}{"status": "OK"}))
+	vc.Reset()
+	vc.Add(newMetric(map[string]interface{}{"response_time": 0.1}))
+	vc.Push(&acc)
+
+	assert.Empty(t, acc.Metrics)
+}
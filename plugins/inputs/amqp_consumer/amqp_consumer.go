@@ -29,6 +29,10 @@ type AMQPConsumer struct {
 	// for consumers before receiving delivery acks.
 	PrefetchCount int
 
+	// Maximum number of messages read from the queue that have not been
+	// written by the outputs, messages are acked once written.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// AMQP Auth method
 	AuthMethod string
 	// Path to CA file
@@ -55,8 +59,9 @@ func (a *externalAuth) Response() string {
 }
 
 const (
-	DefaultAuthMethod    = "PLAIN"
-	DefaultPrefetchCount = 50
+	DefaultAuthMethod             = "PLAIN"
+	DefaultPrefetchCount          = 50
+	DefaultMaxUndeliveredMessages = 1000
 )
 
 func (a *AMQPConsumer) SampleConfig() string {
@@ -73,6 +78,16 @@ func (a *AMQPConsumer) SampleConfig() string {
   ## Maximum number of messages server should give to the worker.
   prefetch_count = 50
 
+  ## Maximum messages to read from the broker that have not been written by
+  ## an output. Messages are acked once their metrics have been written,
+  ## messages that could not be written are rejected.
+  ##
+  ## This value needs to be picked with awareness of the agent's
+  ## metric_batch_size value as well. Setting max undelivered messages too
+  ## high can result in a constant stream of data batches to the output.
+  ## While setting it too low may never flush the broker's messages.
+  # max_undelivered_messages = 1000
+
   ## Auth method. PLAIN and EXTERNAL are supported
   ## Using EXTERNAL requires enabling the rabbitmq_auth_mechanism_ssl plugin as
   ## described here: https://www.rabbitmq.com/plugins.html
@@ -140,6 +155,10 @@ func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	if a.MaxUndeliveredMessages <= 0 {
+		a.MaxUndeliveredMessages = DefaultMaxUndeliveredMessages
+	}
+
 	a.wg = &sync.WaitGroup{}
 	a.wg.Add(1)
 	go a.process(msgs, acc)
@@ -242,22 +261,61 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 	return msgs, err
 }
 
-// Read messages from queue and add them to the Accumulator
+// Read messages from queue and add them to the Accumulator. Each message is
+// acked once its metrics have been written by the outputs.
 func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, acc telegraf.Accumulator) {
 	defer a.wg.Done()
-	for d := range msgs {
-		metrics, err := a.parser.Parse(d.Body)
-		if err != nil {
-			log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
-		} else {
-			for _, m := range metrics {
-				acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+
+	// A new tracking accumulator is used for every connection, as the
+	// deliveries of a closed channel can no longer be acked.
+	tacc := acc.WithTracking(a.MaxUndeliveredMessages)
+	undelivered := make(map[telegraf.TrackingID]amqp.Delivery)
+	for {
+		// Stop reading new messages while too many are undelivered.
+		in := msgs
+		if len(undelivered) >= a.MaxUndeliveredMessages {
+			in = nil
+		}
+
+		select {
+		case info := <-tacc.Delivered():
+			d, ok := undelivered[info.ID()]
+			if !ok {
+				continue
 			}
+			delete(undelivered, info.ID())
+			a.onDelivery(d, info)
+		case d, ok := <-in:
+			if !ok {
+				log.Printf("I! AMQP consumer queue closed")
+				return
+			}
+			metrics, err := a.parser.Parse(d.Body)
+			if err != nil {
+				// The message can never be processed, so it is acked along
+				// with the others rather than redelivered.
+				log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
+				metrics = nil
+			}
+			id := tacc.AddTrackingMetricGroup(metrics)
+			undelivered[id] = d
 		}
+	}
+}
 
-		d.Ack(false)
+func (a *AMQPConsumer) onDelivery(d amqp.Delivery, info telegraf.DeliveryInfo) {
+	var err error
+	if info.Delivered() {
+		err = d.Ack(false)
+	} else {
+		// Requeueing would most likely fail the same way again.
+		log.Printf("W! AMQP consumer, metrics from message %d were not delivered",
+			d.DeliveryTag)
+		err = d.Reject(false)
+	}
+	if err != nil {
+		log.Printf("E! Unable to acknowledge AMQP message %d: %s", d.DeliveryTag, err)
 	}
-	log.Printf("I! AMQP consumer queue closed")
 }
 
 func (a *AMQPConsumer) Stop() {
@@ -273,8 +331,9 @@ func (a *AMQPConsumer) Stop() {
 func init() {
 	inputs.Add("amqp_consumer", func() telegraf.Input {
 		return &AMQPConsumer{
-			AuthMethod:    DefaultAuthMethod,
-			PrefetchCount: DefaultPrefetchCount,
+			AuthMethod:             DefaultAuthMethod,
+			PrefetchCount:          DefaultPrefetchCount,
+			MaxUndeliveredMessages: DefaultMaxUndeliveredMessages,
 		}
 	})
 }
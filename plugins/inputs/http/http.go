@@ -3,6 +3,7 @@ package http
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -15,15 +16,23 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
 
+// maxErrorBodyLen is the number of bytes of a failed response body that are
+// included in the error.
+const maxErrorBodyLen = 256
+
 type HTTP struct {
 	URLs   []string `toml:"urls"`
 	Method string
+	Body   string
 
 	Headers map[string]string
 
 	// HTTP Basic Auth Credentials
 	Username string
-	Password string
+	Password internal.Secret
+
+	// Bearer Token authorization file path
+	BearerToken string `toml:"bearer_token"`
 
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
@@ -52,6 +61,11 @@ var sampleConfig = `
   ## HTTP method
   # method = "GET"
 
+  ## Optional HTTP request body
+  # body = '''
+  # {"query":"SELECT * FROM metrics"}
+  # '''
+
   ## Optional HTTP headers
   # headers = {"X-Special-Header" = "Special-Value"}
 
@@ -59,8 +73,8 @@ var sampleConfig = `
   # username = "username"
   # password = "pa$$word"
 
-  ## Tag all metrics with the url
-  # tag_url = true
+  ## Use bearer token for authorization, read from the given file
+  # bearer_token = "/path/to/bearer/token"
 
   ## Optional SSL Config
   # ssl_ca = "/etc/telegraf/ca.pem"
@@ -143,7 +157,12 @@ func (h *HTTP) gatherURL(
 	acc telegraf.Accumulator,
 	url string,
 ) error {
-	request, err := http.NewRequest(h.Method, url, nil)
+	var body io.Reader
+	if h.Body != "" {
+		body = strings.NewReader(h.Body)
+	}
+
+	request, err := http.NewRequest(h.Method, url, body)
 	if err != nil {
 		return err
 	}
@@ -156,8 +175,21 @@ func (h *HTTP) gatherURL(
 		}
 	}
 
-	if h.Username != "" || h.Password != "" {
-		request.SetBasicAuth(h.Username, h.Password)
+	password, err := h.Password.Get()
+	if err != nil {
+		return err
+	}
+	if h.Username != "" || password != "" {
+		request.SetBasicAuth(h.Username, password)
+	}
+
+	if h.BearerToken != "" {
+		token, err := ioutil.ReadFile(h.BearerToken)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Authorization",
+			"Bearer "+strings.TrimSpace(string(token)))
 	}
 
 	resp, err := h.client.Do(request)
@@ -166,17 +198,18 @@ func (h *HTTP) gatherURL(
 	}
 	defer resp.Body.Close()
 
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Received status code %d (%s), expected %d (%s)",
+		return fmt.Errorf("Received status code %d (%s), expected %d (%s)%s",
 			resp.StatusCode,
 			http.StatusText(resp.StatusCode),
 			http.StatusOK,
-			http.StatusText(http.StatusOK))
-	}
-
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+			http.StatusText(http.StatusOK),
+			excerpt(b))
 	}
 
 	metrics, err := h.parser.Parse(b)
@@ -194,6 +227,19 @@ func (h *HTTP) gatherURL(
 	return nil
 }
 
+// excerpt returns the start of a response body, to be appended to an error
+// message, or an empty string if the body is empty.
+func excerpt(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if s == "" {
+		return ""
+	}
+	if len(s) > maxErrorBodyLen {
+		s = s[:maxErrorBodyLen] + "..."
+	}
+	return ": " + s
+}
+
 func init() {
 	inputs.Add("http", func() telegraf.Input {
 		return &HTTP{
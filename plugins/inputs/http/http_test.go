@@ -1,8 +1,10 @@
 package http_test
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 
 	plugin "github.com/influxdata/telegraf/plugins/inputs/http"
@@ -113,6 +115,61 @@ func TestMethod(t *testing.T) {
 	require.NoError(t, acc.GatherError(plugin.Gather))
 }
 
+func TestBodyAndBearerToken(t *testing.T) {
+	tokenFile, err := ioutil.TempFile("", "telegraf-token")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	_, err = tokenFile.WriteString("abc123\n")
+	require.NoError(t, err)
+	require.NoError(t, tokenFile.Close())
+
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) == "{}" && r.Header.Get("Authorization") == "Bearer abc123" {
+			_, _ = w.Write([]byte(simpleJSON))
+		} else {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer fakeServer.Close()
+
+	plugin := &plugin.HTTP{
+		URLs:        []string{fakeServer.URL},
+		Method:      "POST",
+		Body:        "{}",
+		BearerToken: tokenFile.Name(),
+	}
+
+	p, _ := parsers.NewJSONParser("metricName", nil, nil)
+	plugin.SetParser(p)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Len(t, acc.Metrics, 1)
+}
+
+func TestInvalidStatusCodeReportsResponse(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("invalid token\n"))
+	}))
+	defer fakeServer.Close()
+
+	plugin := &plugin.HTTP{
+		URLs: []string{fakeServer.URL},
+	}
+
+	p, _ := parsers.NewJSONParser("metricName", nil, nil)
+	plugin.SetParser(p)
+
+	var acc testutil.Accumulator
+	err := acc.GatherError(plugin.Gather)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fakeServer.URL)
+	require.Contains(t, err.Error(), "401 (Unauthorized)")
+	require.Contains(t, err.Error(), ": invalid token")
+}
+
 func TestParserNotSet(t *testing.T) {
 	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/endpoint" {
@@ -15,6 +15,8 @@ import (
 	cluster "github.com/bsm/sarama-cluster"
 )
 
+const defaultMaxUndeliveredMessages = 1000
+
 type Kafka struct {
 	ConsumerGroup string
 	Topics        []string
@@ -35,7 +37,7 @@ type Kafka struct {
 	// SASL Username
 	SASLUsername string `toml:"sasl_username"`
 	// SASL Password
-	SASLPassword string `toml:"sasl_password"`
+	SASLPassword internal.Secret `toml:"sasl_password"`
 
 	// Legacy metric buffer support
 	MetricBuffer int
@@ -45,6 +47,10 @@ type Kafka struct {
 	Offset string
 	parser parsers.Parser
 
+	// MaxUndeliveredMessages is the maximum number of messages read from
+	// Kafka that have not yet been written by the outputs.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	sync.Mutex
 
 	// channel for all incoming kafka messages
@@ -54,13 +60,33 @@ type Kafka struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
+
+	// messages waiting for their metrics to be delivered, the offset of a
+	// message is only marked once its metrics have been written.
+	undelivered map[telegraf.TrackingID]*trackedMessage
+	// messages of each partition in offset order, that are undelivered or
+	// delivered after an earlier message that is still undelivered.
+	partitions map[topicPartition][]*trackedMessage
 
 	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
 	// this is mostly for test purposes, but there may be a use-case for it later.
 	doNotCommitMsgs bool
 }
 
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// trackedMessage is a message read from Kafka whose offset has not been
+// marked yet.
+type trackedMessage struct {
+	topicPartition
+	offset    int64
+	delivered bool
+}
+
 var sampleConfig = `
   ## kafka servers
   brokers = ["localhost:9092"]
@@ -92,6 +118,17 @@ var sampleConfig = `
   ## Maximum length of a message to consume, in bytes (default 0/unlimited);
   ## larger messages are dropped
   max_message_len = 65536
+
+  ## Maximum messages to read from the broker that have not been written by
+  ## an output. The offset of a message is committed once its metrics and
+  ## those of all earlier messages of the partition have been written, so
+  ## that unwritten messages are consumed again after a restart.
+  ##
+  ## This value needs to be picked with awareness of the agent's
+  ## metric_batch_size value as well. Setting max undelivered messages too
+  ## high can result in a constant stream of data batches to the output.
+  ## While setting it too low may never flush the broker's messages.
+  # max_undelivered_messages = 1000
 `
 
 func (k *Kafka) SampleConfig() string {
@@ -111,7 +148,10 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 	defer k.Unlock()
 	var clusterErr error
 
-	k.acc = acc
+	if k.MaxUndeliveredMessages <= 0 {
+		k.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 
 	config := cluster.NewConfig()
 	config.Consumer.Return.Errors = true
@@ -127,11 +167,15 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 		config.Net.TLS.Config = tlsConfig
 		config.Net.TLS.Enable = true
 	}
-	if k.SASLUsername != "" && k.SASLPassword != "" {
+	password, err := k.SASLPassword.Get()
+	if err != nil {
+		return err
+	}
+	if k.SASLUsername != "" && password != "" {
 		log.Printf("D! Using SASL auth with username '%s',",
 			k.SASLUsername)
 		config.Net.SASL.User = k.SASLUsername
-		config.Net.SASL.Password = k.SASLPassword
+		config.Net.SASL.Password = password
 		config.Net.SASL.Enable = true
 	}
 
@@ -176,6 +220,8 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 // receiver() reads all incoming messages from the consumer, and parses them into
 // influxdb metric points.
 func (k *Kafka) receiver() {
+	k.undelivered = make(map[telegraf.TrackingID]*trackedMessage)
+	k.partitions = make(map[topicPartition][]*trackedMessage)
 	for {
 		select {
 		case <-k.done:
@@ -184,32 +230,104 @@ func (k *Kafka) receiver() {
 			if err != nil {
 				k.acc.AddError(fmt.Errorf("Consumer Error: %s\n", err))
 			}
-		case msg := <-k.in:
+		case info := <-k.acc.Delivered():
+			k.onDelivery(info)
+		case msg := <-k.messages():
+			var metrics []telegraf.Metric
 			if k.MaxMessageLen != 0 && len(msg.Value) > k.MaxMessageLen {
 				k.acc.AddError(fmt.Errorf("Message longer than max_message_len (%d > %d)",
 					len(msg.Value), k.MaxMessageLen))
 			} else {
-				metrics, err := k.parser.Parse(msg.Value)
+				var err error
+				metrics, err = k.parser.Parse(msg.Value)
 				if err != nil {
 					k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
 						string(msg.Value), err.Error()))
 				}
-				for _, metric := range metrics {
-					k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-				}
 			}
 
-			if !k.doNotCommitMsgs {
-				// TODO(cam) this locking can be removed if this PR gets merged:
-				// https://github.com/wvanbergen/kafka/pull/84
-				k.Lock()
-				k.Cluster.MarkOffset(msg, "")
-				k.Unlock()
-			}
+			// Messages without metrics are tracked as well, so that their
+			// offset is marked in order with the others.
+			id := k.acc.AddTrackingMetricGroup(metrics)
+			k.track(id, msg)
 		}
 	}
 }
 
+// messages returns the channel of incoming messages, or nil while the maximum
+// number of undelivered messages are in flight.
+func (k *Kafka) messages() <-chan *sarama.ConsumerMessage {
+	if len(k.undelivered) >= k.MaxUndeliveredMessages {
+		return nil
+	}
+	return k.in
+}
+
+// track adds a message to the undelivered messages.
+func (k *Kafka) track(id telegraf.TrackingID, msg *sarama.ConsumerMessage) {
+	tm := &trackedMessage{
+		topicPartition: topicPartition{topic: msg.Topic, partition: msg.Partition},
+		offset:         msg.Offset,
+	}
+	k.undelivered[id] = tm
+	k.partitions[tm.topicPartition] = append(k.partitions[tm.topicPartition], tm)
+}
+
+// onDelivery marks the offset of the message once its metrics, and those of
+// all earlier messages of the same partition, have been written by the
+// outputs. Messages whose metrics could not be written are never marked, so
+// that they are consumed again after a restart.
+func (k *Kafka) onDelivery(info telegraf.DeliveryInfo) {
+	tm, ok := k.undelivered[info.ID()]
+	if !ok {
+		return
+	}
+	delete(k.undelivered, info.ID())
+
+	if !info.Delivered() {
+		log.Printf("W! Kafka consumer, metrics from topic %s partition %d "+
+			"offset %d were not delivered, no later offsets of the partition "+
+			"are committed until the consumer is restarted",
+			tm.topic, tm.partition, tm.offset)
+		return
+	}
+	tm.delivered = true
+
+	offset, ok := k.deliveredOffset(tm.topicPartition)
+	if ok && !k.doNotCommitMsgs {
+		// TODO(cam) this locking can be removed if this PR gets merged:
+		// https://github.com/wvanbergen/kafka/pull/84
+		k.Lock()
+		k.Cluster.MarkOffset(&sarama.ConsumerMessage{
+			Topic:     tm.topic,
+			Partition: tm.partition,
+			Offset:    offset,
+		}, "")
+		k.Unlock()
+	}
+}
+
+// deliveredOffset removes the delivered messages at the start of the
+// partition, and returns the highest offset among them.
+func (k *Kafka) deliveredOffset(tp topicPartition) (int64, bool) {
+	pending := k.partitions[tp]
+	n := 0
+	for n < len(pending) && pending[n].delivered {
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+
+	offset := pending[n-1].offset
+	if n == len(pending) {
+		delete(k.partitions, tp)
+	} else {
+		k.partitions[tp] = pending[n:]
+	}
+	return offset, true
+}
+
 func (k *Kafka) Stop() {
 	k.Lock()
 	defer k.Unlock()
@@ -225,6 +343,8 @@ func (k *Kafka) Gather(acc telegraf.Accumulator) error {
 
 func init() {
 	inputs.Add("kafka_consumer", func() telegraf.Input {
-		return &Kafka{}
+		return &Kafka{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
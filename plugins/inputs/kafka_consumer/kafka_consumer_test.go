@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -29,6 +30,8 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 		doNotCommitMsgs: true,
 		errs:            make(chan error, 1000),
 		done:            make(chan struct{}),
+
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 	}
 	return &k, in
 }
@@ -37,7 +40,7 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 func TestRunParser(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -52,7 +55,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -69,7 +72,7 @@ func TestDropOverlongMsg(t *testing.T) {
 	k, in := newTestKafka()
 	k.MaxMessageLen = maxMessageLen
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 	overlongMsg := strings.Repeat("v", maxMessageLen+1)
 
@@ -84,7 +87,7 @@ func TestDropOverlongMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -103,7 +106,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -122,7 +125,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewJSONParser("kafka_json_test", []string{}, nil)
@@ -140,6 +143,69 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// Test that no messages are read while too many are undelivered
+func TestMaxUndeliveredMessages(t *testing.T) {
+	k, _ := newTestKafka()
+	k.MaxUndeliveredMessages = 1
+	k.undelivered = make(map[telegraf.TrackingID]*trackedMessage)
+	k.partitions = make(map[topicPartition][]*trackedMessage)
+	assert.NotNil(t, k.messages())
+
+	k.track(1, saramaMsg(testMsg))
+	assert.Nil(t, k.messages())
+}
+
+// Test that offsets are only marked up to the first undelivered message
+func TestDeliveredOffsetOrder(t *testing.T) {
+	k, _ := newTestKafka()
+	k.undelivered = make(map[telegraf.TrackingID]*trackedMessage)
+	k.partitions = make(map[topicPartition][]*trackedMessage)
+	tp := topicPartition{topic: "telegraf"}
+	for i := 0; i < 4; i++ {
+		msg := saramaMsg(testMsg)
+		msg.Topic = "telegraf"
+		msg.Offset = int64(i)
+		k.track(telegraf.TrackingID(i), msg)
+	}
+
+	// Delivered out of order, offset 0 is still being written.
+	k.onDelivery(&deliveryInfo{id: 1, delivered: true})
+	k.onDelivery(&deliveryInfo{id: 2, delivered: true})
+	_, ok := k.deliveredOffset(tp)
+	assert.False(t, ok)
+
+	k.undelivered[0].delivered = true
+	delete(k.undelivered, 0)
+	offset, ok := k.deliveredOffset(tp)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), offset)
+	assert.Len(t, k.partitions[tp], 1)
+
+	// A rejected message holds back all later offsets.
+	k.onDelivery(&deliveryInfo{id: 3, delivered: false})
+	assert.Len(t, k.partitions[tp], 1)
+	assert.Empty(t, k.undelivered)
+
+	k.track(4, &sarama.ConsumerMessage{Topic: "telegraf", Offset: 4})
+	k.onDelivery(&deliveryInfo{id: 4, delivered: true})
+	_, ok = k.deliveredOffset(tp)
+	assert.False(t, ok)
+	assert.Len(t, k.partitions[tp], 2)
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d *deliveryInfo) ID() telegraf.TrackingID {
+	return d.id
+}
+
+func (d *deliveryInfo) Delivered() bool {
+	return d.delivered
+}
+
 func saramaMsg(val string) *sarama.ConsumerMessage {
 	return &sarama.ConsumerMessage{
 		Key:       nil,
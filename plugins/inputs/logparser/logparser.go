@@ -8,11 +8,14 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/influxdata/tail"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/internal/multiline"
+	"github.com/influxdata/telegraf/internal/tailstate"
 	"github.com/influxdata/telegraf/plugins/inputs"
 
 	// Parsers
@@ -39,13 +42,18 @@ type LogParserPlugin struct {
 	Files         []string
 	FromBeginning bool
 	WatchMethod   string
-
-	tailers map[string]*tail.Tail
-	lines   chan logEntry
-	done    chan struct{}
-	wg      sync.WaitGroup
-	acc     telegraf.Accumulator
-	parsers []LogParser
+	StateFile     string
+	Multiline     *multiline.Config
+
+	tailers   map[string]*tail.Tail
+	trackers  map[string]*tailstate.Tracker
+	state     *tailstate.State
+	multiline *multiline.Multiline
+	lines     chan logEntry
+	wg        sync.WaitGroup
+	parserWg  sync.WaitGroup
+	acc       telegraf.Accumulator
+	parsers   []LogParser
 
 	sync.Mutex
 
@@ -69,6 +77,28 @@ const sampleConfig = `
   ## Method used to watch for file updates.  Can be either "inotify" or "poll".
   # watch_method = "inotify"
 
+  ## File to persist the read offsets in, so that files are read on from
+  ## where they were left after a restart. Files that were rotated or
+  ## truncated meanwhile are read from the beginning. Each logparser plugin
+  ## needs its own state file.
+  # state_file = "/var/lib/telegraf/logparser.state"
+
+  ## Join the lines of events spanning several lines, such as stack traces,
+  ## into single events before parsing them. The lines of an event are
+  ## joined with newlines.
+  # [inputs.logparser.multiline]
+    ## Regular expression matching the lines that belong to a neighbouring
+    ## line, e.g. the indented lines of a stack trace.
+    # pattern = '^\s'
+    ## The neighbour the matching lines belong to, "previous" or "next".
+    # match_which_line = "previous"
+    ## Whether the lines that do not match the pattern belong to their
+    ## neighbour instead.
+    # invert_match = false
+    ## How long an incomplete event waits for more lines before it is
+    ## parsed anyway.
+    # timeout = "5s"
+
   ## Parse logstash-style "grok" patterns:
   ##   Telegraf built-in parsing patterns: https://goo.gl/dkay10
   [inputs.logparser.grok]
@@ -117,6 +147,12 @@ func (l *LogParserPlugin) Gather(acc telegraf.Accumulator) error {
 	l.Lock()
 	defer l.Unlock()
 
+	// Offsets are saved on every gather as well, to lose as little as
+	// possible if telegraf does not stop cleanly.
+	if err := l.saveOffsets(); err != nil {
+		acc.AddError(err)
+	}
+
 	// always start from the beginning of files that appear while we're running
 	return l.tailNewfiles(true)
 }
@@ -128,8 +164,8 @@ func (l *LogParserPlugin) Start(acc telegraf.Accumulator) error {
 
 	l.acc = acc
 	l.lines = make(chan logEntry, 1000)
-	l.done = make(chan struct{})
 	l.tailers = make(map[string]*tail.Tail)
+	l.trackers = make(map[string]*tailstate.Tracker)
 
 	// Looks for fields which implement LogParser interface
 	l.parsers = []LogParser{}
@@ -160,7 +196,26 @@ func (l *LogParserPlugin) Start(acc telegraf.Accumulator) error {
 		}
 	}
 
-	l.wg.Add(1)
+	l.multiline = nil
+	if l.Multiline != nil {
+		var err error
+		l.multiline, err = multiline.New(*l.Multiline)
+		if err != nil {
+			return fmt.Errorf("logparser input plugin: %s", err)
+		}
+	}
+
+	if l.StateFile != "" {
+		var err error
+		l.state, err = tailstate.Load(l.StateFile)
+		if err != nil {
+			log.Printf("E! Error loading logparser state %s, starting afresh: %s",
+				l.StateFile, err)
+			l.state = tailstate.New(l.StateFile)
+		}
+	}
+
+	l.parserWg.Add(1)
 	go l.parser()
 
 	return l.tailNewfiles(l.FromBeginning)
@@ -195,19 +250,33 @@ func (l *LogParserPlugin) tailNewfiles(fromBeginning bool) error {
 				continue
 			}
 
+			location := seek
+			if l.state != nil {
+				if offset, ok := l.state.Offset(file); ok {
+					location = tail.SeekInfo{
+						Whence: 0,
+						Offset: offset,
+					}
+				}
+			}
+
+			tracker := tailstate.NewTracker(file)
 			tailer, err := tail.TailFile(file,
 				tail.Config{
 					ReOpen:    true,
 					Follow:    true,
-					Location:  &seek,
+					Location:  &location,
 					MustExist: true,
 					Poll:      poll,
-					Logger:    tail.DiscardingLogger,
+					Logger:    tracker,
 				})
 			if err != nil {
 				l.acc.AddError(err)
 				continue
 			}
+			// The file has been opened by TailFile, as it must exist.
+			tracker.Opened()
+			l.trackers[file] = tracker
 
 			// create a goroutine for each "tailer"
 			l.wg.Add(1)
@@ -224,8 +293,27 @@ func (l *LogParserPlugin) tailNewfiles(fromBeginning bool) error {
 func (l *LogParserPlugin) receiver(tailer *tail.Tail) {
 	defer l.wg.Done()
 
-	var line *tail.Line
-	for line = range tailer.Lines {
+	var joiner *multiline.Joiner
+	var timeout <-chan time.Time
+	if l.multiline != nil {
+		joiner = l.multiline.Joiner()
+		timeout = joiner.Timeout()
+	}
+
+	for {
+		var line *tail.Line
+		var ok bool
+		select {
+		case line, ok = <-tailer.Lines:
+		case <-timeout:
+			if event, ok := joiner.Flush(); ok {
+				l.send(tailer, event)
+			}
+			continue
+		}
+		if !ok {
+			break
+		}
 
 		if line.Err != nil {
 			log.Printf("E! Error tailing file %s, Error: %s\n",
@@ -236,35 +324,41 @@ func (l *LogParserPlugin) receiver(tailer *tail.Tail) {
 		// Fix up files with Windows line endings.
 		text := strings.TrimRight(line.Text, "\r")
 
-		entry := logEntry{
-			path: tailer.Filename,
-			line: text,
+		if joiner != nil {
+			if text, ok = joiner.Add(text); !ok {
+				continue
+			}
 		}
-
-		select {
-		case <-l.done:
-		case l.lines <- entry:
+		l.send(tailer, text)
+	}
+	if joiner != nil {
+		if event, ok := joiner.Flush(); ok {
+			l.send(tailer, event)
 		}
 	}
 }
 
+// send sends a log line of tailer down the l.lines channel.
+func (l *LogParserPlugin) send(tailer *tail.Tail, text string) {
+	entry := logEntry{
+		path: tailer.Filename,
+		line: text,
+	}
+
+	l.lines <- entry
+}
+
 // parser is launched as a goroutine to watch the l.lines channel.
 // when a line is available, parser parses it and adds the metric(s) to the
-// accumulator.
+// accumulator. It returns once l.lines is closed and drained.
 func (l *LogParserPlugin) parser() {
-	defer l.wg.Done()
+	defer l.parserWg.Done()
 
 	var m telegraf.Metric
 	var err error
-	var entry logEntry
-	for {
-		select {
-		case <-l.done:
-			return
-		case entry = <-l.lines:
-			if entry.line == "" || entry.line == "\n" {
-				continue
-			}
+	for entry := range l.lines {
+		if entry.line == "" || entry.line == "\n" {
+			continue
 		}
 		for _, parser := range l.parsers {
 			m, err = parser.ParseLine(entry.line)
@@ -286,6 +380,9 @@ func (l *LogParserPlugin) Stop() {
 	l.Lock()
 	defer l.Unlock()
 
+	if err := l.saveOffsets(); err != nil {
+		log.Printf("E! %s", err)
+	}
 	for _, t := range l.tailers {
 		err := t.Stop()
 		if err != nil {
@@ -293,8 +390,33 @@ func (l *LogParserPlugin) Stop() {
 		}
 		t.Cleanup()
 	}
-	close(l.done)
+	// The receivers send the events still buffered by their joiners once
+	// their tailer is stopped, so they are waited for before the lines are
+	// closed; the parser then parses every line sent before returning.
 	l.wg.Wait()
+	close(l.lines)
+	l.parserWg.Wait()
+}
+
+// saveOffsets saves the current offset of every file to the state file, if
+// there is one. Assumes l's lock is held!
+func (l *LogParserPlugin) saveOffsets() error {
+	if l.state == nil {
+		return nil
+	}
+	for _, t := range l.tailers {
+		// Tell reports 0 until the file has been opened, and whatever was
+		// recorded before is better than that.
+		offset, err := t.Tell()
+		if err != nil || offset == 0 {
+			continue
+		}
+		l.state.Set(t.Filename, l.trackers[t.Filename].Inode(), offset)
+	}
+	if err := l.state.Save(); err != nil {
+		return fmt.Errorf("Error saving logparser state %s: %s", l.StateFile, err)
+	}
+	return nil
 }
 
 func init() {
@@ -7,11 +7,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/influxdata/telegraf/internal/multiline"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/influxdata/telegraf/plugins/inputs/logparser/grok"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStartNoParsers(t *testing.T) {
@@ -85,6 +87,41 @@ func TestGrokParseLogFiles(t *testing.T) {
 		})
 }
 
+func TestGrokParseLogFilesMultiline(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString("panic: oops\n" +
+		"\tat main.go:10\n" +
+		"\tat main.go:20\n" +
+		"ok\n")
+	require.NoError(t, err)
+	defer tmpfile.Close()
+
+	p := &grok.Parser{
+		Patterns:       []string{"%{EVENT:message}"},
+		CustomPatterns: "EVENT (?s).*",
+	}
+
+	logparser := &LogParserPlugin{
+		FromBeginning: true,
+		Files:         []string{tmpfile.Name()},
+		Multiline:     &multiline.Config{Pattern: `^\s`},
+		GrokParser:    p,
+	}
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, logparser.Start(&acc))
+	acc.Wait(1)
+	// The last event is incomplete until telegraf stops.
+	logparser.Stop()
+
+	require.Len(t, acc.Metrics, 2)
+	assert.Equal(t, "panic: oops\n\tat main.go:10\n\tat main.go:20",
+		acc.Metrics[0].Fields["message"])
+	assert.Equal(t, "ok", acc.Metrics[1].Fields["message"])
+}
+
 func TestGrokParseLogFilesAppearLater(t *testing.T) {
 	emptydir, err := ioutil.TempDir("", "TestGrokParseLogFilesAppearLater")
 	defer os.RemoveAll(emptydir)
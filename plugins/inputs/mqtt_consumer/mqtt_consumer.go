@@ -18,6 +18,8 @@ import (
 // 30 Seconds is the default used by paho.mqtt.golang
 var defaultConnectionTimeout = internal.Duration{Duration: 30 * time.Second}
 
+const defaultMaxUndeliveredMessages = 1000
+
 type MQTTConsumer struct {
 	Servers           []string
 	Topics            []string
@@ -26,6 +28,9 @@ type MQTTConsumer struct {
 	QoS               int               `toml:"qos"`
 	ConnectionTimeout internal.Duration `toml:"connection_timeout"`
 
+	// Maximum number of messages that have not been written by the outputs.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	parser parsers.Parser
 
 	// Legacy metric buffer support
@@ -50,7 +55,7 @@ type MQTTConsumer struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
 
 	connected bool
 }
@@ -65,6 +70,16 @@ var sampleConfig = `
   ## Connection timeout for initial connection in seconds
   connection_timeout = "30s"
 
+  ## Maximum messages to read from the broker that have not been written by
+  ## an output. While this many messages are in flight no new messages are
+  ## read, which in turn causes the broker to hold back further messages.
+  ##
+  ## This value needs to be picked with awareness of the agent's
+  ## metric_batch_size value as well. Setting max undelivered messages too
+  ## high can result in a constant stream of data batches to the output.
+  ## While setting it too low may never flush the broker's messages.
+  # max_undelivered_messages = 1000
+
   ## Topics to subscribe to
   topics = [
     "telegraf/host01/cpu",
@@ -119,7 +134,10 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 			" = true, you MUST also set client_id")
 	}
 
-	m.acc = acc
+	if m.MaxUndeliveredMessages <= 0 {
+		m.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	m.acc = acc.WithTracking(m.MaxUndeliveredMessages)
 	if m.QoS > 2 || m.QoS < 0 {
 		return fmt.Errorf("MQTT Consumer, invalid QoS value: %d", m.QoS)
 	}
@@ -181,11 +199,20 @@ func (m *MQTTConsumer) onConnectionLost(c mqtt.Client, err error) {
 // receiver() reads all incoming messages from the consumer, and parses them into
 // influxdb metric points.
 func (m *MQTTConsumer) receiver() {
+	undelivered := make(map[telegraf.TrackingID]bool)
 	for {
+		// Stop reading new messages while too many are undelivered.
+		in := m.in
+		if len(undelivered) >= m.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-m.done:
 			return
-		case msg := <-m.in:
+		case info := <-m.acc.Delivered():
+			delete(undelivered, info.ID())
+		case msg := <-in:
 			topic := msg.Topic()
 			metrics, err := m.parser.Parse(msg.Payload())
 			if err != nil {
@@ -194,10 +221,10 @@ func (m *MQTTConsumer) receiver() {
 			}
 
 			for _, metric := range metrics {
-				tags := metric.Tags()
-				tags["topic"] = topic
-				m.acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+				metric.AddTag("topic", topic)
 			}
+			id := m.acc.AddTrackingMetricGroup(metrics)
+			undelivered[id] = true
 		}
 	}
 }
@@ -284,7 +311,8 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 func init() {
 	inputs.Add("mqtt_consumer", func() telegraf.Input {
 		return &MQTTConsumer{
-			ConnectionTimeout: defaultConnectionTimeout,
+			ConnectionTimeout:      defaultConnectionTimeout,
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
@@ -11,6 +11,8 @@ import (
 	"github.com/nats-io/nats"
 )
 
+const defaultMaxUndeliveredMessages = 1000
+
 type natsError struct {
 	conn *nats.Conn
 	sub  *nats.Subscription
@@ -32,6 +34,9 @@ type natsConsumer struct {
 	PendingMessageLimit int
 	PendingBytesLimit   int
 
+	// Maximum number of messages that have not been written by the outputs.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// Legacy metric buffer support
 	MetricBuffer int
 
@@ -47,7 +52,7 @@ type natsConsumer struct {
 	// channel for all NATS read errors
 	errs chan error
 	done chan struct{}
-	acc  telegraf.Accumulator
+	acc  telegraf.TrackingAccumulator
 }
 
 var sampleConfig = `
@@ -65,6 +70,16 @@ var sampleConfig = `
   # pending_message_limit = 65536
   # pending_bytes_limit = 67108864
 
+  ## Maximum messages to read from the server that have not been written by
+  ## an output. While this many messages are in flight no new messages are
+  ## read, and further messages are held in the client's pending buffer.
+  ##
+  ## This value needs to be picked with awareness of the agent's
+  ## metric_batch_size value as well. Setting max undelivered messages too
+  ## high can result in a constant stream of data batches to the output.
+  ## While setting it too low may never flush the broker's messages.
+  # max_undelivered_messages = 1000
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -97,7 +112,10 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 	n.Lock()
 	defer n.Unlock()
 
-	n.acc = acc
+	if n.MaxUndeliveredMessages <= 0 {
+		n.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 
 	var connectErr error
 
@@ -157,21 +175,29 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 // telegraf metrics.
 func (n *natsConsumer) receiver() {
 	defer n.wg.Done()
+	undelivered := make(map[telegraf.TrackingID]bool)
 	for {
+		// Stop reading new messages while too many are undelivered.
+		in := n.in
+		if len(undelivered) >= n.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-n.done:
 			return
 		case err := <-n.errs:
 			n.acc.AddError(fmt.Errorf("E! error reading from %s\n", err.Error()))
-		case msg := <-n.in:
+		case info := <-n.acc.Delivered():
+			delete(undelivered, info.ID())
+		case msg := <-in:
 			metrics, err := n.parser.Parse(msg.Data)
 			if err != nil {
 				n.acc.AddError(fmt.Errorf("E! subject: %s, error: %s", msg.Subject, err.Error()))
 			}
 
-			for _, metric := range metrics {
-				n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-			}
+			id := n.acc.AddTrackingMetricGroup(metrics)
+			undelivered[id] = true
 		}
 	}
 }
@@ -210,6 +236,8 @@ func init() {
 			QueueGroup:          "telegraf_consumers",
 			PendingBytesLimit:   nats.DefaultSubPendingBytesLimit,
 			PendingMessageLimit: nats.DefaultSubPendingMsgsLimit,
+
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
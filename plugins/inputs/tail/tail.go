@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/influxdata/tail"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/internal/multiline"
+	"github.com/influxdata/telegraf/internal/tailstate"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
@@ -24,11 +27,16 @@ type Tail struct {
 	FromBeginning bool
 	Pipe          bool
 	WatchMethod   string
+	StateFile     string
+	Multiline     *multiline.Config
 
-	tailers []*tail.Tail
-	parser  parsers.Parser
-	wg      sync.WaitGroup
-	acc     telegraf.Accumulator
+	tailers   []*tail.Tail
+	trackers  map[string]*tailstate.Tracker
+	state     *tailstate.State
+	multiline *multiline.Multiline
+	parser    parsers.Parser
+	wg        sync.WaitGroup
+	acc       telegraf.Accumulator
 
 	sync.Mutex
 }
@@ -55,6 +63,12 @@ const sampleConfig = `
   ## Whether file is a named pipe
   pipe = false
 
+  ## File to persist the read offsets in, so that files are read on from
+  ## where they were left after a restart. Files that were rotated or
+  ## truncated meanwhile are read from the beginning. Each tail plugin needs
+  ## its own state file.
+  # state_file = "/var/lib/telegraf/tail.state"
+
   ## Method used to watch for file updates.  Can be either "inotify" or "poll".
   # watch_method = "inotify"
 
@@ -63,6 +77,22 @@ const sampleConfig = `
   ## more about them here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
   data_format = "influx"
+
+  ## Join the lines of events spanning several lines, such as stack traces,
+  ## into single events before parsing them. The lines of an event are
+  ## joined with newlines.
+  # [inputs.tail.multiline]
+    ## Regular expression matching the lines that belong to a neighbouring
+    ## line, e.g. the indented lines of a stack trace.
+    # pattern = '^\s'
+    ## The neighbour the matching lines belong to, "previous" or "next".
+    # match_which_line = "previous"
+    ## Whether the lines that do not match the pattern belong to their
+    ## neighbour instead.
+    # invert_match = false
+    ## How long an incomplete event waits for more lines before it is
+    ## parsed anyway.
+    # timeout = "5s"
 `
 
 func (t *Tail) SampleConfig() string {
@@ -74,7 +104,12 @@ func (t *Tail) Description() string {
 }
 
 func (t *Tail) Gather(acc telegraf.Accumulator) error {
-	return nil
+	t.Lock()
+	defer t.Unlock()
+
+	// Offsets are saved on every gather as well, to lose as little as
+	// possible if telegraf does not stop cleanly.
+	return t.saveOffsets()
 }
 
 func (t *Tail) Start(acc telegraf.Accumulator) error {
@@ -82,6 +117,16 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 	defer t.Unlock()
 
 	t.acc = acc
+	t.trackers = make(map[string]*tailstate.Tracker)
+
+	t.multiline = nil
+	if t.Multiline != nil {
+		var err error
+		t.multiline, err = multiline.New(*t.Multiline)
+		if err != nil {
+			return fmt.Errorf("tail input plugin: %s", err)
+		}
+	}
 
 	var seek *tail.SeekInfo
 	if !t.Pipe && !t.FromBeginning {
@@ -96,6 +141,16 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 		poll = true
 	}
 
+	if t.StateFile != "" && !t.Pipe {
+		var err error
+		t.state, err = tailstate.Load(t.StateFile)
+		if err != nil {
+			acc.AddError(fmt.Errorf("E! Error loading tail state %s, starting afresh: %s",
+				t.StateFile, err))
+			t.state = tailstate.New(t.StateFile)
+		}
+	}
+
 	// Create a "tailer" for each file
 	for _, filepath := range t.Files {
 		g, err := globpath.Compile(filepath)
@@ -103,20 +158,34 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 			t.acc.AddError(fmt.Errorf("E! Error Glob %s failed to compile, %s", filepath, err))
 		}
 		for file, _ := range g.Match() {
+			location := seek
+			if t.state != nil {
+				if offset, ok := t.state.Offset(file); ok {
+					location = &tail.SeekInfo{
+						Whence: 0,
+						Offset: offset,
+					}
+				}
+			}
+
+			tracker := tailstate.NewTracker(file)
 			tailer, err := tail.TailFile(file,
 				tail.Config{
 					ReOpen:    true,
 					Follow:    true,
-					Location:  seek,
+					Location:  location,
 					MustExist: true,
 					Poll:      poll,
 					Pipe:      t.Pipe,
-					Logger:    tail.DiscardingLogger,
+					Logger:    tracker,
 				})
 			if err != nil {
 				acc.AddError(err)
 				continue
 			}
+			// The file has been opened by TailFile, as it must exist.
+			tracker.Opened()
+			t.trackers[file] = tracker
 			// create a goroutine for each "tailer"
 			t.wg.Add(1)
 			go t.receiver(tailer)
@@ -132,24 +201,46 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 func (t *Tail) receiver(tailer *tail.Tail) {
 	defer t.wg.Done()
 
-	var m telegraf.Metric
-	var err error
-	var line *tail.Line
-	for line = range tailer.Lines {
+	var joiner *multiline.Joiner
+	var timeout <-chan time.Time
+	if t.multiline != nil {
+		joiner = t.multiline.Joiner()
+		timeout = joiner.Timeout()
+	}
+
+	for {
+		var line *tail.Line
+		var ok bool
+		select {
+		case line, ok = <-tailer.Lines:
+		case <-timeout:
+			if event, ok := joiner.Flush(); ok {
+				t.parseLine(tailer, event)
+			}
+			continue
+		}
+		if !ok {
+			break
+		}
+
 		if line.Err != nil {
 			t.acc.AddError(fmt.Errorf("E! Error tailing file %s, Error: %s\n",
-				tailer.Filename, err))
+				tailer.Filename, line.Err))
 			continue
 		}
 		// Fix up files with Windows line endings.
 		text := strings.TrimRight(line.Text, "\r")
 
-		m, err = t.parser.ParseLine(text)
-		if err == nil {
-			t.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
-		} else {
-			t.acc.AddError(fmt.Errorf("E! Malformed log line in %s: [%s], Error: %s\n",
-				tailer.Filename, line.Text, err))
+		if joiner != nil {
+			if text, ok = joiner.Add(text); !ok {
+				continue
+			}
+		}
+		t.parseLine(tailer, text)
+	}
+	if joiner != nil {
+		if event, ok := joiner.Flush(); ok {
+			t.parseLine(tailer, event)
 		}
 	}
 	if err := tailer.Err(); err != nil {
@@ -158,10 +249,23 @@ func (t *Tail) receiver(tailer *tail.Tail) {
 	}
 }
 
+func (t *Tail) parseLine(tailer *tail.Tail, text string) {
+	m, err := t.parser.ParseLine(text)
+	if err == nil {
+		t.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	} else {
+		t.acc.AddError(fmt.Errorf("E! Malformed log line in %s: [%s], Error: %s\n",
+			tailer.Filename, text, err))
+	}
+}
+
 func (t *Tail) Stop() {
 	t.Lock()
 	defer t.Unlock()
 
+	if err := t.saveOffsets(); err != nil {
+		t.acc.AddError(err)
+	}
 	for _, tailer := range t.tailers {
 		err := tailer.Stop()
 		if err != nil {
@@ -172,6 +276,27 @@ func (t *Tail) Stop() {
 	t.wg.Wait()
 }
 
+// saveOffsets saves the current offset of every file to the state file, if
+// there is one. Assumes t's lock is held!
+func (t *Tail) saveOffsets() error {
+	if t.state == nil {
+		return nil
+	}
+	for _, tailer := range t.tailers {
+		// Tell reports 0 until the file has been opened, and whatever was
+		// recorded before is better than that.
+		offset, err := tailer.Tell()
+		if err != nil || offset == 0 {
+			continue
+		}
+		t.state.Set(tailer.Filename, t.trackers[tailer.Filename].Inode(), offset)
+	}
+	if err := t.state.Save(); err != nil {
+		return fmt.Errorf("E! Error saving tail state %s: %s", t.StateFile, err)
+	}
+	return nil
+}
+
 func (t *Tail) SetParser(parser parsers.Parser) {
 	t.parser = parser
 }
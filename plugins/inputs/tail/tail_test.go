@@ -3,9 +3,11 @@ package tail
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
+	"github.com/influxdata/telegraf/internal/multiline"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -141,3 +143,134 @@ func TestTailDosLineendings(t *testing.T) {
 			"usage_idle": float64(200),
 		})
 }
+
+func TestTailResumeFromState(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	defer tmpfile.Close()
+	stateFile := tmpfile.Name() + ".state"
+	defer os.Remove(stateFile)
+
+	_, err = tmpfile.WriteString("cpu,mytag=foo usage_idle=100\n")
+	require.NoError(t, err)
+
+	run := func() *testutil.Accumulator {
+		tt := NewTail()
+		tt.FromBeginning = true
+		tt.StateFile = stateFile
+		tt.Files = []string{tmpfile.Name()}
+		p, _ := parsers.NewInfluxParser()
+		tt.SetParser(p)
+
+		acc := testutil.Accumulator{}
+		require.NoError(t, tt.Start(&acc))
+		acc.Wait(1)
+		tt.Stop()
+		return &acc
+	}
+
+	acc := run()
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_idle": float64(100)},
+		map[string]string{"mytag": "foo"})
+
+	// Lines written while stopped are read, earlier ones are not read again.
+	_, err = tmpfile.WriteString("cpu,mytag=bar usage_idle=50\n")
+	require.NoError(t, err)
+
+	acc = run()
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_idle": float64(50)},
+		map[string]string{"mytag": "bar"})
+}
+
+func TestTailResumeFromStateAfterRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logFile := filepath.Join(dir, "cpu.log")
+	stateFile := filepath.Join(dir, "state.json")
+
+	require.NoError(t, ioutil.WriteFile(logFile,
+		[]byte("cpu,mytag=foo usage_idle=100\n"), 0644))
+
+	start := func() (*Tail, *testutil.Accumulator) {
+		tt := NewTail()
+		tt.FromBeginning = true
+		tt.StateFile = stateFile
+		tt.Files = []string{logFile}
+		p, _ := parsers.NewInfluxParser()
+		tt.SetParser(p)
+
+		acc := testutil.Accumulator{}
+		require.NoError(t, tt.Start(&acc))
+		return tt, &acc
+	}
+
+	tt, acc := start()
+	acc.Wait(1)
+
+	// The file is rotated while it is tailed, the new file is followed.
+	require.NoError(t, os.Rename(logFile, logFile+".1"))
+	require.NoError(t, ioutil.WriteFile(logFile,
+		[]byte("cpu,mytag=bar usage_idle=50\n"), 0644))
+	acc.Wait(2)
+	tt.Stop()
+
+	// The offset saved is that of the new file, so it is not read again.
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("cpu,mytag=baz usage_idle=25\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	tt, acc = start()
+	acc.Wait(1)
+	tt.Stop()
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_idle": float64(25)},
+		map[string]string{"mytag": "baz"})
+}
+
+func TestTailMultiline(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString("panic: oops\n" +
+		"\tat main.go:10\r\n" +
+		"\tat main.go:20\n" +
+		"ok\n")
+	require.NoError(t, err)
+
+	tt := NewTail()
+	tt.FromBeginning = true
+	tt.Files = []string{tmpfile.Name()}
+	tt.Multiline = &multiline.Config{Pattern: `^\s`}
+	p, _ := parsers.NewValueParser("log", "string", nil)
+	tt.SetParser(p)
+	defer tmpfile.Close()
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, tt.Start(&acc))
+	acc.Wait(1)
+	// The last event is incomplete until telegraf stops.
+	tt.Stop()
+
+	require.Len(t, acc.Metrics, 2)
+	assert.Equal(t, "panic: oops\n\tat main.go:10\n\tat main.go:20",
+		acc.Metrics[0].Fields["value"])
+	assert.Equal(t, "ok", acc.Metrics[1].Fields["value"])
+}
+
+func TestTailMultilineInvalid(t *testing.T) {
+	tt := NewTail()
+	tt.Multiline = &multiline.Config{Pattern: `(`}
+	p, _ := parsers.NewInfluxParser()
+	tt.SetParser(p)
+
+	acc := testutil.Accumulator{}
+	assert.Error(t, tt.Start(&acc))
+}
@@ -90,15 +90,13 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
-	for _, metric := range metrics {
-		b, err := f.serializer.Serialize(metric)
-		if err != nil {
-			return fmt.Errorf("failed to serialize message: %s", err)
-		}
-		_, err = f.writer.Write(b)
-		if err != nil {
-			return fmt.Errorf("failed to write message: %s, %s", metric.Serialize(), err)
-		}
+	b, err := f.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %s", err)
+	}
+	_, err = f.writer.Write(b)
+	if err != nil {
+		return fmt.Errorf("failed to write message: %s", err)
 	}
 	return nil
 }
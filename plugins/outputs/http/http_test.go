@@ -0,0 +1,131 @@
+package http
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newTestHTTP(t *testing.T, url string) *HTTP {
+	s, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	h := &HTTP{
+		URL:        url,
+		Timeout:    internal.Duration{Duration: 5 * time.Second},
+		serializer: s,
+	}
+	return h
+}
+
+func TestHTTPWrite(t *testing.T) {
+	var method, body, contentType, user, pass, header string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+		header = r.Header.Get("X-Special-Header")
+		user, pass, _ = r.BasicAuth()
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(t, ts.URL)
+	h.Method = "put"
+	h.Username = "telegraf"
+	h.Password = internal.NewSecret("secret")
+	h.Headers = map[string]string{"X-Special-Header": "Special-Value"}
+	require.NoError(t, h.Connect())
+
+	metrics := testutil.MockMetrics()
+	require.NoError(t, h.Write(metrics))
+
+	assert.Equal(t, "PUT", method)
+	assert.Equal(t, defaultContentType, contentType)
+	assert.Equal(t, "Special-Value", header)
+	assert.Equal(t, "telegraf", user)
+	assert.Equal(t, "secret", pass)
+	expected, err := h.serializer.SerializeBatch(metrics)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), body)
+}
+
+func TestHTTPWriteGzipAndBearerToken(t *testing.T) {
+	tokenFile, err := ioutil.TempFile("", "telegraf-token")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	tokenFile.WriteString("abc123\n")
+	tokenFile.Close()
+
+	var body, auth, encoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		encoding = r.Header.Get("Content-Encoding")
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := ioutil.ReadAll(gr)
+		body = string(b)
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(t, ts.URL)
+	h.ContentEncoding = "gzip"
+	h.BearerToken = tokenFile.Name()
+	require.NoError(t, h.Connect())
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+
+	assert.Equal(t, "Bearer abc123", auth)
+	assert.Equal(t, "gzip", encoding)
+	assert.Contains(t, body, "test1,tag1=value1 value=1")
+}
+
+func TestHTTPWriteErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(t, ts.URL)
+	require.NoError(t, h.Connect())
+	assert.Error(t, h.Write(testutil.MockMetrics()))
+}
+
+func TestHTTPWriteErrorBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid field format\n" + strings.Repeat("x", 1000)))
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(t, ts.URL)
+	require.NoError(t, h.Connect())
+	err := h.Write(testutil.MockMetrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400: invalid field format")
+	assert.True(t, len(err.Error()) < 400)
+}
+
+func TestHTTPInvalidConfig(t *testing.T) {
+	h := newTestHTTP(t, "http://localhost")
+	h.Method = "GET"
+	assert.Error(t, h.Connect())
+
+	h = newTestHTTP(t, "http://localhost")
+	h.ContentEncoding = "deflate"
+	assert.Error(t, h.Connect())
+}
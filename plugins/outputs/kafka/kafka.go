@@ -57,7 +57,7 @@ type (
 		// SASL Username
 		SASLUsername string `toml:"sasl_username"`
 		// SASL Password
-		SASLPassword string `toml:"sasl_password"`
+		SASLPassword internal.Secret `toml:"sasl_password"`
 
 		tlsConfig tls.Config
 		producer  sarama.SyncProducer
@@ -216,9 +216,13 @@ func (k *Kafka) Connect() error {
 		config.Net.TLS.Enable = true
 	}
 
-	if k.SASLUsername != "" && k.SASLPassword != "" {
+	password, err := k.SASLPassword.Get()
+	if err != nil {
+		return err
+	}
+	if k.SASLUsername != "" && password != "" {
 		config.Net.SASL.User = k.SASLUsername
-		config.Net.SASL.Password = k.SASLPassword
+		config.Net.SASL.Password = password
 		config.Net.SASL.Enable = true
 	}
 
@@ -57,7 +57,7 @@ type PrometheusClient struct {
 	TLSCert            string            `toml:"tls_cert"`
 	TLSKey             string            `toml:"tls_key"`
 	BasicUsername      string            `toml:"basic_username"`
-	BasicPassword      string            `toml:"basic_password"`
+	BasicPassword      internal.Secret   `toml:"basic_password"`
 	ExpirationInterval internal.Duration `toml:"expiration_interval"`
 	Path               string            `toml:"path"`
 	CollectorsExclude  []string          `toml:"collectors_exclude"`
@@ -96,15 +96,15 @@ var sampleConfig = `
   string_as_label = true
 `
 
-func (p *PrometheusClient) basicAuth(h http.Handler) http.Handler {
+func (p *PrometheusClient) basicAuth(h http.Handler, basicPassword string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if p.BasicUsername != "" && p.BasicPassword != "" {
+		if p.BasicUsername != "" && basicPassword != "" {
 			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
 
 			username, password, ok := r.BasicAuth()
 			if !ok ||
 				subtle.ConstantTimeCompare([]byte(username), []byte(p.BasicUsername)) != 1 ||
-				subtle.ConstantTimeCompare([]byte(password), []byte(p.BasicPassword)) != 1 {
+				subtle.ConstantTimeCompare([]byte(password), []byte(basicPassword)) != 1 {
 				http.Error(w, "Not authorized", 401)
 				return
 			}
@@ -146,8 +146,13 @@ func (p *PrometheusClient) Start() error {
 	}
 
 	mux := http.NewServeMux()
+	basicPassword, err := p.BasicPassword.Get()
+	if err != nil {
+		return err
+	}
 	mux.Handle(p.Path, p.basicAuth(promhttp.HandlerFor(
-		registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})))
+		registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}),
+		basicPassword))
 
 	p.server = &http.Server{
 		Addr:    p.Listen,
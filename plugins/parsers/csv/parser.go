@@ -0,0 +1,257 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Parser parses comma separated values, one metric per record.
+type Parser struct {
+	MetricName string
+	// HeaderRowCount is the number of rows naming the columns. The names of
+	// several header rows are concatenated.
+	HeaderRowCount int
+	// SkipRows is the number of rows to skip before the header.
+	SkipRows int
+	// SkipColumns is the number of leading columns to skip.
+	SkipColumns int
+	// Delimiter separates the columns, "," by default.
+	Delimiter string
+	// Comment starts lines to ignore.
+	Comment string
+	// TrimSpace removes the white space around values.
+	TrimSpace bool
+	// ColumnNames names the columns and takes precedence over the header.
+	ColumnNames []string
+	// ColumnTypes are the types of the columns, one of "int", "float",
+	// "bool" and "string". Without them, the type is guessed per value.
+	ColumnTypes       []string
+	TagColumns        []string
+	MeasurementColumn string
+	TimestampColumn   string
+	// TimestampFormat is "unix", "unix_ms", "unix_us", "unix_ns" or a Go time
+	// layout.
+	TimestampFormat string
+	DefaultTags     map[string]string
+	TimeFunc        func() time.Time
+}
+
+func (p *Parser) reader(r io.Reader) (*csv.Reader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = p.TrimSpace
+	if p.Delimiter != "" {
+		delimiter, size := utf8.DecodeRuneInString(p.Delimiter)
+		if size != len(p.Delimiter) {
+			return nil, fmt.Errorf("csv delimiter %q must be a single character", p.Delimiter)
+		}
+		reader.Comma = delimiter
+	}
+	if p.Comment != "" {
+		comment, size := utf8.DecodeRuneInString(p.Comment)
+		if size != len(p.Comment) {
+			return nil, fmt.Errorf("csv comment %q must be a single character", p.Comment)
+		}
+		reader.Comment = comment
+	}
+	return reader, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	for i := 0; i < p.SkipRows && len(buf) > 0; i++ {
+		if n := bytes.IndexByte(buf, '\n'); n >= 0 {
+			buf = buf[n+1:]
+		} else {
+			buf = nil
+		}
+	}
+
+	reader, err := p.reader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	var header []string
+	for i := 0; i < p.HeaderRowCount; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil, fmt.Errorf("csv header of %d rows expected, found %d", p.HeaderRowCount, i)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for j, name := range record {
+			if p.TrimSpace {
+				name = strings.TrimSpace(name)
+			}
+			if j < len(header) {
+				header[j] += name
+			} else {
+				header = append(header, name)
+			}
+		}
+	}
+	columns := p.ColumnNames
+	if len(columns) == 0 {
+		if len(header) <= p.SkipColumns {
+			return nil, fmt.Errorf("csv column names must be given by csv_column_names or a header row")
+		}
+		columns = header[p.SkipColumns:]
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		m, err := p.parseRecord(columns, record)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a single record. Since lines parsed one by one cannot
+// have a header, the column names must be given by ColumnNames.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	if len(p.ColumnNames) == 0 {
+		return nil, fmt.Errorf("csv column names must be given by csv_column_names to parse single lines")
+	}
+	reader, err := p.reader(strings.NewReader(line))
+	if err != nil {
+		return nil, err
+	}
+	record, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: csv", line)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.parseRecord(p.ColumnNames, record)
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) parseRecord(columns []string, record []string) (telegraf.Metric, error) {
+	if len(record) < p.SkipColumns {
+		return nil, fmt.Errorf("csv record has %d columns, fewer than the %d to skip",
+			len(record), p.SkipColumns)
+	}
+	record = record[p.SkipColumns:]
+
+	name := p.MetricName
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	var timestamp string
+
+outer:
+	for i, value := range record {
+		if i >= len(columns) {
+			break
+		}
+		column := columns[i]
+		if p.TrimSpace {
+			value = strings.TrimSpace(value)
+		}
+
+		if p.MeasurementColumn != "" && column == p.MeasurementColumn {
+			name = value
+			continue
+		}
+		if p.TimestampColumn != "" && column == p.TimestampColumn {
+			timestamp = value
+			continue
+		}
+		for _, tag := range p.TagColumns {
+			if column == tag {
+				tags[column] = value
+				continue outer
+			}
+		}
+		// An empty value is a missing field.
+		if value == "" {
+			continue
+		}
+
+		var typ string
+		if i < len(p.ColumnTypes) {
+			typ = p.ColumnTypes[i]
+		}
+		v, err := parseValue(typ, value)
+		if err != nil {
+			return nil, fmt.Errorf("csv column %s: %s", column, err)
+		}
+		fields[column] = v
+	}
+
+	t := p.now()
+	if p.TimestampColumn != "" {
+		if p.TimestampFormat == "" {
+			return nil, fmt.Errorf("csv_timestamp_format is required with csv_timestamp_column")
+		}
+		var err error
+		t, err = internal.ParseTimestamp(p.TimestampFormat, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("csv column %s: %s", p.TimestampColumn, err)
+		}
+	}
+
+	return metric.New(name, tags, fields, t)
+}
+
+func (p *Parser) now() time.Time {
+	if p.TimeFunc != nil {
+		return p.TimeFunc()
+	}
+	return time.Now().UTC()
+}
+
+// parseValue converts value to typ, or to the first of integer, float and
+// boolean it is when typ is empty, and keeps it a string otherwise.
+func parseValue(typ, value string) (interface{}, error) {
+	switch typ {
+	case "":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v, nil
+		}
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v, nil
+		}
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v, nil
+		}
+		return value, nil
+	case "int":
+		return strconv.ParseInt(value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	case "bool":
+		return strconv.ParseBool(value)
+	case "string":
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unknown column type %q", typ)
+	}
+}
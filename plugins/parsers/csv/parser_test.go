@@ -0,0 +1,144 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1518640000, 0).UTC()
+
+func nowFunc() time.Time {
+	return now
+}
+
+func TestHeader(t *testing.T) {
+	p := Parser{
+		MetricName:     "csv",
+		HeaderRowCount: 1,
+		TagColumns:     []string{"host"},
+		DefaultTags:    map[string]string{"source": "test"},
+		TimeFunc:       nowFunc,
+	}
+	metrics, err := p.Parse([]byte("host,usage,ok,note\n" +
+		"a,42.5,true,fine\n" +
+		"b,7,false,\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "csv", metrics[0].Name())
+	assert.Equal(t, map[string]string{"host": "a", "source": "test"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"usage": 42.5,
+		"ok":    true,
+		"note":  "fine",
+	}, metrics[0].Fields())
+	assert.Equal(t, now.UnixNano(), metrics[0].Time().UnixNano())
+
+	// Empty values are missing fields.
+	assert.Equal(t, map[string]interface{}{
+		"usage": int64(7),
+		"ok":    false,
+	}, metrics[1].Fields())
+}
+
+func TestMultipleHeaderRows(t *testing.T) {
+	p := Parser{
+		MetricName:     "csv",
+		HeaderRowCount: 2,
+		SkipRows:       1,
+		TimeFunc:       nowFunc,
+	}
+	metrics, err := p.Parse([]byte("exported by legacy tool\n" +
+		"cpu_,mem_\n" +
+		"usage,used\n" +
+		"10,20\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"cpu_usage": int64(10),
+		"mem_used":  int64(20),
+	}, metrics[0].Fields())
+}
+
+func TestColumnOptions(t *testing.T) {
+	p := Parser{
+		MetricName:        "csv",
+		SkipColumns:       1,
+		Delimiter:         ";",
+		Comment:           "#",
+		TrimSpace:         true,
+		ColumnNames:       []string{"name", "time", "code", "value"},
+		ColumnTypes:       []string{"string", "string", "string", "float"},
+		MeasurementColumn: "name",
+		TimestampColumn:   "time",
+		TimestampFormat:   "2006-01-02 15:04:05",
+	}
+	metrics, err := p.Parse([]byte("# a comment\n" +
+		"1; disk ; 2018-02-14 20:26:40 ; 007 ; 1\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "disk", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"code":  "007",
+		"value": float64(1),
+	}, metrics[0].Fields())
+	assert.Equal(t, now.UnixNano(), metrics[0].Time().UnixNano())
+}
+
+func TestQuotedValues(t *testing.T) {
+	p := Parser{
+		MetricName:  "csv",
+		ColumnNames: []string{"message", "count"},
+		TimeFunc:    nowFunc,
+	}
+	metrics, err := p.Parse([]byte(`"hello, ""world""",3` + "\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"message": `hello, "world"`,
+		"count":   int64(3),
+	}, metrics[0].Fields())
+}
+
+func TestParseLine(t *testing.T) {
+	p := Parser{
+		MetricName:      "csv",
+		ColumnNames:     []string{"time", "value"},
+		TimestampColumn: "time",
+		TimestampFormat: "unix",
+	}
+	m, err := p.ParseLine("1518640000,5")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"value": int64(5)}, m.Fields())
+	assert.Equal(t, now.UnixNano(), m.Time().UnixNano())
+
+	p = Parser{MetricName: "csv", HeaderRowCount: 1}
+	_, err = p.ParseLine("1518640000,5")
+	assert.Error(t, err)
+}
+
+func TestInvalidValues(t *testing.T) {
+	p := Parser{
+		MetricName:  "csv",
+		ColumnNames: []string{"value"},
+		ColumnTypes: []string{"int"},
+	}
+	_, err := p.Parse([]byte("1.5\n"))
+	assert.Error(t, err)
+
+	p = Parser{
+		MetricName:      "csv",
+		ColumnNames:     []string{"time", "value"},
+		TimestampColumn: "time",
+		TimestampFormat: "unix",
+	}
+	_, err = p.Parse([]byte("yesterday,1\n"))
+	assert.Error(t, err)
+
+	p = Parser{MetricName: "csv", HeaderRowCount: 1}
+	_, err = p.Parse([]byte(""))
+	assert.Error(t, err)
+}
@@ -6,19 +6,50 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/tidwall/gjson"
 )
 
 type JSONParser struct {
 	MetricName  string
 	TagKeys     []string
 	DefaultTags map[string]string
+
+	// Query is a GJSON path selecting the object or array of objects to
+	// parse, instead of the whole document.
+	Query string
+	// StringFields are the keys, which may be globs, of the string values
+	// to keep as fields. Other string values are ignored.
+	StringFields []string
+	// NameKey is the key of the value to name the metric after.
+	NameKey string
+	// TimeKey is the key of the timestamp of the metric, in TimeFormat:
+	// "unix", "unix_ms", "unix_us", "unix_ns" or a Go time layout.
+	TimeKey    string
+	TimeFormat string
+
+	// stringFields is StringFields compiled on first use.
+	stringFieldsOnce sync.Once
+	stringFields     filter.Filter
+	stringFieldsErr  error
+}
+
+// stringFieldsFilter returns the filter of StringFields, compiling it only
+// once.
+func (p *JSONParser) stringFieldsFilter() (filter.Filter, error) {
+	p.stringFieldsOnce.Do(func() {
+		p.stringFields, p.stringFieldsErr = filter.Compile(p.StringFields)
+	})
+	return p.stringFields, p.stringFieldsErr
 }
 
-func (p *JSONParser) parseArray(buf []byte) ([]telegraf.Metric, error) {
+func (p *JSONParser) parseArray(buf []byte, stringFields filter.Filter) ([]telegraf.Metric, error) {
 	metrics := make([]telegraf.Metric, 0)
 
 	var jsonOut []map[string]interface{}
@@ -28,12 +59,19 @@ func (p *JSONParser) parseArray(buf []byte) ([]telegraf.Metric, error) {
 		return nil, err
 	}
 	for _, item := range jsonOut {
-		metrics, err = p.parseObject(metrics, item)
+		metrics, err = p.parseObject(metrics, item, stringFields)
+		if err != nil {
+			return nil, err
+		}
 	}
 	return metrics, nil
 }
 
-func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]interface{}) ([]telegraf.Metric, error) {
+func (p *JSONParser) parseObject(
+	metrics []telegraf.Metric,
+	jsonOut map[string]interface{},
+	stringFields filter.Filter,
+) ([]telegraf.Metric, error) {
 
 	tags := make(map[string]string)
 	for k, v := range p.DefaultTags {
@@ -52,13 +90,45 @@ func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]i
 		delete(jsonOut, tag)
 	}
 
+	name := p.MetricName
+	if p.NameKey != "" {
+		if v, ok := jsonOut[p.NameKey].(string); ok && v != "" {
+			name = v
+		}
+		delete(jsonOut, p.NameKey)
+	}
+
+	t := time.Now().UTC()
+	if p.TimeKey != "" {
+		if p.TimeFormat == "" {
+			return nil, fmt.Errorf("json_time_format is required with json_time_key")
+		}
+		// Objects without the key keep the default time, so that one of
+		// them does not fail the whole batch.
+		if v, ok := jsonOut[p.TimeKey]; ok {
+			var err error
+			t, err = internal.ParseTimestamp(p.TimeFormat, v)
+			if err != nil {
+				return nil, fmt.Errorf("JSON time key %q: %s", p.TimeKey, err)
+			}
+			delete(jsonOut, p.TimeKey)
+		}
+	}
+
 	f := JSONFlattener{}
-	err := f.FlattenJSON("", jsonOut)
+	err := f.FullFlattenJSON("", jsonOut, stringFields != nil, false)
 	if err != nil {
 		return nil, err
 	}
+	if stringFields != nil {
+		for k, v := range f.Fields {
+			if _, ok := v.(string); ok && !stringFields.Match(k) {
+				delete(f.Fields, k)
+			}
+		}
+	}
 
-	metric, err := metric.New(p.MetricName, tags, f.Fields, time.Now().UTC())
+	metric, err := metric.New(name, tags, f.Fields, t)
 
 	if err != nil {
 		return nil, err
@@ -67,11 +137,24 @@ func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]i
 }
 
 func (p *JSONParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if p.Query != "" {
+		result := gjson.GetBytes(buf, p.Query)
+		if result.Type != gjson.JSON {
+			return nil, fmt.Errorf("JSON query %q must select an object or an array of objects", p.Query)
+		}
+		buf = []byte(result.Raw)
+	}
+
 	buf = bytes.TrimSpace(buf)
 	if len(buf) == 0 {
 		return make([]telegraf.Metric, 0), nil
 	}
 
+	stringFields, err := p.stringFieldsFilter()
+	if err != nil {
+		return nil, fmt.Errorf("invalid json_string_fields: %s", err)
+	}
+
 	if !isarray(buf) {
 		metrics := make([]telegraf.Metric, 0)
 		var jsonOut map[string]interface{}
@@ -80,9 +163,9 @@ func (p *JSONParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 			err = fmt.Errorf("unable to parse out as JSON, %s", err)
 			return nil, err
 		}
-		return p.parseObject(metrics, jsonOut)
+		return p.parseObject(metrics, jsonOut, stringFields)
 	}
-	return p.parseArray(buf)
+	return p.parseArray(buf, stringFields)
 }
 
 func (p *JSONParser) ParseLine(line string) (telegraf.Metric, error) {
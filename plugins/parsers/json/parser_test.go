@@ -2,8 +2,10 @@ package json
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -428,3 +430,111 @@ func TestParseArrayWithTagKeys(t *testing.T) {
 		"othertag": "baz",
 	}, metrics[1].Tags())
 }
+
+const validJSONQuery = `
+{
+    "status": "ok",
+    "data": {
+        "services": [
+            {
+                "service": "api",
+                "state": "running",
+                "version": "1.2.3",
+                "requests": 120,
+                "timestamp": 1518640000
+            },
+            {
+                "service": "worker",
+                "state": "stopped",
+                "version": "1.2.4",
+                "requests": 0,
+                "timestamp": 1518640001
+            }
+        ]
+    }
+}
+`
+
+func TestParseWithQuery(t *testing.T) {
+	parser := JSONParser{
+		MetricName:   "json_test",
+		Query:        "data.services",
+		StringFields: []string{"stat*"},
+		NameKey:      "service",
+		TimeKey:      "timestamp",
+		TimeFormat:   "unix",
+	}
+
+	metrics, err := parser.Parse([]byte(validJSONQuery))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 2)
+
+	assert.Equal(t, "api", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"state":    "running",
+		"requests": float64(120),
+	}, metrics[0].Fields())
+	assert.Equal(t, int64(1518640000), metrics[0].Time().Unix())
+
+	assert.Equal(t, "worker", metrics[1].Name())
+	assert.Equal(t, int64(1518640001), metrics[1].Time().Unix())
+}
+
+func TestParseWithQueryObject(t *testing.T) {
+	parser := JSONParser{
+		MetricName: "json_test",
+		Query:      "data.services.0",
+		TagKeys:    []string{"service"},
+	}
+
+	metrics, err := parser.Parse([]byte(validJSONQuery))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "json_test", metrics[0].Name())
+	assert.Equal(t, map[string]string{"service": "api"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"requests":  float64(120),
+		"timestamp": float64(1518640000),
+	}, metrics[0].Fields())
+
+	// The query must select an object or an array.
+	parser.Query = "status"
+	_, err = parser.Parse([]byte(validJSONQuery))
+	assert.Error(t, err)
+}
+
+func TestParseWithTimeLayout(t *testing.T) {
+	parser := JSONParser{
+		MetricName: "json_test",
+		TimeKey:    "time",
+		TimeFormat: "2006-01-02T15:04:05Z07:00",
+	}
+
+	metrics, err := parser.Parse([]byte(`{"a": 5, "time": "2018-02-14T20:26:40Z"}`))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"a": float64(5)}, metrics[0].Fields())
+	assert.Equal(t, int64(1518640000), metrics[0].Time().Unix())
+
+	// Timestamps must be valid.
+	_, err = parser.Parse([]byte(`{"a": 5, "time": "yesterday"}`))
+	assert.Error(t, err)
+}
+
+func TestParseWithMissingTimeKey(t *testing.T) {
+	parser := JSONParser{
+		MetricName: "json_test",
+		TimeKey:    "time",
+		TimeFormat: "unix",
+	}
+
+	before := time.Now()
+	metrics, err := parser.Parse([]byte(`[{"a": 5, "time": 1518640000}, {"a": 6}]`))
+	assert.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, int64(1518640000), metrics[0].Time().Unix())
+
+	// The object without the key is timestamped with the time it is parsed.
+	assert.Equal(t, map[string]interface{}{"a": float64(6)}, metrics[1].Fields())
+	assert.False(t, metrics[1].Time().Before(before))
+}
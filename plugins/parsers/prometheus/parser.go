@@ -21,9 +21,20 @@ import (
 	"github.com/prometheus/common/expfmt"
 )
 
+// Parser parses the Prometheus exposition formats. Each sample becomes a
+// metric named after its metric family, except for summaries and histograms,
+// which become a single metric with a field per quantile or bucket.
+type Parser struct {
+	DefaultTags map[string]string
+	// Header is the HTTP header the metrics were received with. Its
+	// Content-Type tells whether they are in the protocol buffer format
+	// rather than the text format.
+	Header http.Header
+}
+
 // Parse returns a slice of Metrics from a text representation of a
 // metrics
-func Parse(buf []byte, header http.Header) ([]telegraf.Metric, error) {
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 	var metrics []telegraf.Metric
 	var parser expfmt.TextParser
 	// parse even if the buffer begins with a newline
@@ -32,7 +43,7 @@ func Parse(buf []byte, header http.Header) ([]telegraf.Metric, error) {
 	buffer := bytes.NewBuffer(buf)
 	reader := bufio.NewReader(buffer)
 
-	mediatype, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	mediatype, params, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
 	// Prepare output
 	metricFamilies := make(map[string]*dto.MetricFamily)
 
@@ -61,6 +72,11 @@ func Parse(buf []byte, header http.Header) ([]telegraf.Metric, error) {
 		for _, m := range mf.Metric {
 			// reading tags
 			tags := makeLabels(m)
+			for k, v := range p.DefaultTags {
+				if _, ok := tags[k]; !ok {
+					tags[k] = v
+				}
+			}
 			// reading fields
 			fields := make(map[string]interface{})
 			if mf.GetType() == dto.MetricType_SUMMARY {
@@ -97,6 +113,25 @@ func Parse(buf []byte, header http.Header) ([]telegraf.Metric, error) {
 	return metrics, err
 }
 
+// ParseLine parses a single sample, which is untyped unless preceded by its
+// TYPE comment.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line + "\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: prometheus ", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
 func valueType(mt dto.MetricType) telegraf.ValueType {
 	switch mt {
 	case dto.MetricType_COUNTER:
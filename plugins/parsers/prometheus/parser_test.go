@@ -1,7 +1,6 @@
 package prometheus
 
 import (
-	"net/http"
 	"testing"
 	"time"
 
@@ -102,8 +101,10 @@ cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
 `
 
 func TestParseValidPrometheus(t *testing.T) {
+	parser := Parser{}
+
 	// Gauge value
-	metrics, err := Parse([]byte(validUniqueGauge), http.Header{})
+	metrics, err := parser.Parse([]byte(validUniqueGauge))
 	assert.NoError(t, err)
 	assert.Len(t, metrics, 1)
 	assert.Equal(t, "cadvisor_version_info", metrics[0].Name())
@@ -117,7 +118,7 @@ func TestParseValidPrometheus(t *testing.T) {
 	}, metrics[0].Tags())
 
 	// Counter value
-	metrics, err = Parse([]byte(validUniqueCounter), http.Header{})
+	metrics, err = parser.Parse([]byte(validUniqueCounter))
 	assert.NoError(t, err)
 	assert.Len(t, metrics, 1)
 	assert.Equal(t, "get_token_fail_count", metrics[0].Name())
@@ -128,7 +129,7 @@ func TestParseValidPrometheus(t *testing.T) {
 
 	// Summary data
 	//SetDefaultTags(map[string]string{})
-	metrics, err = Parse([]byte(validUniqueSummary), http.Header{})
+	metrics, err = parser.Parse([]byte(validUniqueSummary))
 	assert.NoError(t, err)
 	assert.Len(t, metrics, 1)
 	assert.Equal(t, "http_request_duration_microseconds", metrics[0].Name())
@@ -142,7 +143,7 @@ func TestParseValidPrometheus(t *testing.T) {
 	assert.Equal(t, map[string]string{"handler": "prometheus"}, metrics[0].Tags())
 
 	// histogram data
-	metrics, err = Parse([]byte(validUniqueHistogram), http.Header{})
+	metrics, err = parser.Parse([]byte(validUniqueHistogram))
 	assert.NoError(t, err)
 	assert.Len(t, metrics, 1)
 	assert.Equal(t, "apiserver_request_latencies", metrics[0].Name())
@@ -163,3 +164,16 @@ func TestParseValidPrometheus(t *testing.T) {
 		metrics[0].Tags())
 
 }
+
+func TestParseLineDefaultTags(t *testing.T) {
+	parser := Parser{DefaultTags: map[string]string{"host": "localhost", "job": "default"}}
+
+	m, err := parser.ParseLine(`http_requests_total{job="api"} 1027`)
+	assert.NoError(t, err)
+	assert.Equal(t, "http_requests_total", m.Name())
+	assert.Equal(t, map[string]interface{}{"value": float64(1027)}, m.Fields())
+	assert.Equal(t, map[string]string{"host": "localhost", "job": "api"}, m.Tags())
+
+	_, err = parser.ParseLine(`not a sample`)
+	assert.Error(t, err)
+}
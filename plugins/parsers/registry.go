@@ -4,13 +4,16 @@ import (
 	"fmt"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
+	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
 	"github.com/influxdata/telegraf/plugins/parsers/graphite"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 )
 
@@ -42,7 +45,8 @@ type Parser interface {
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
-	// Dataformat can be one of: json, influx, graphite, value, nagios
+	// Dataformat can be one of: json, influx, graphite, value, nagios, csv,
+	// prometheus
 	DataFormat string
 
 	// Separator only applied to Graphite data.
@@ -52,6 +56,16 @@ type Config struct {
 
 	// TagKeys only apply to JSON data
 	TagKeys []string
+	// JSONQuery is a GJSON path selecting the object or array of objects to
+	// parse
+	JSONQuery string
+	// JSONStringFields are the keys of the string values to keep as fields
+	JSONStringFields []string
+	// JSONNameKey is the key of the value naming the metric
+	JSONNameKey string
+	// JSONTimeKey is the key of the timestamp, in JSONTimeFormat
+	JSONTimeKey    string
+	JSONTimeFormat string
 	// MetricName applies to JSON & value. This will be the name of the measurement.
 	MetricName string
 
@@ -83,6 +97,30 @@ type Config struct {
 	// an optional map containing tag names as keys and json paths to retrieve the tag values from as values
 	// used if TagsPath is empty or doesn't return any tags
 	DropwizardTagPathsMap map[string]string
+
+	// number of rows naming the columns, concatenated if there are several
+	CSVHeaderRowCount int
+	// number of rows to skip before the header
+	CSVSkipRows int
+	// number of leading columns to skip
+	CSVSkipColumns int
+	// the column delimiter, defaults to ","
+	CSVDelimiter string
+	// the character starting lines to ignore
+	CSVComment string
+	// whether to trim the white space around values
+	CSVTrimSpace bool
+	// the names of the columns, taking precedence over the header
+	CSVColumnNames []string
+	// the types of the columns: int, float, bool or string
+	CSVColumnTypes []string
+	// the columns to add as tags
+	CSVTagColumns []string
+	// the column holding the measurement name
+	CSVMeasurementColumn string
+	// the column holding the timestamp, and its format
+	CSVTimestampColumn string
+	CSVTimestampFormat string
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -91,8 +129,14 @@ func NewParser(config *Config) (Parser, error) {
 	var parser Parser
 	switch config.DataFormat {
 	case "json":
-		parser, err = NewJSONParser(config.MetricName,
-			config.TagKeys, config.DefaultTags)
+		parser, err = newJSONParser(config.MetricName,
+			config.TagKeys,
+			config.JSONQuery,
+			config.JSONStringFields,
+			config.JSONNameKey,
+			config.JSONTimeKey,
+			config.JSONTimeFormat,
+			config.DefaultTags)
 	case "value":
 		parser, err = NewValueParser(config.MetricName,
 			config.DataType, config.DefaultTags)
@@ -110,6 +154,23 @@ func NewParser(config *Config) (Parser, error) {
 		parser, err = NewDropwizardParser(config.DropwizardMetricRegistryPath,
 			config.DropwizardTimePath, config.DropwizardTimeFormat, config.DropwizardTagsPath, config.DropwizardTagPathsMap, config.DefaultTags,
 			config.Separator, config.Templates)
+	case "prometheus":
+		parser, err = NewPrometheusParser(config.DefaultTags)
+	case "csv":
+		parser, err = newCSVParser(config.MetricName,
+			config.CSVHeaderRowCount,
+			config.CSVSkipRows,
+			config.CSVSkipColumns,
+			config.CSVDelimiter,
+			config.CSVComment,
+			config.CSVTrimSpace,
+			config.CSVColumnNames,
+			config.CSVColumnTypes,
+			config.CSVTagColumns,
+			config.CSVMeasurementColumn,
+			config.CSVTimestampColumn,
+			config.CSVTimestampFormat,
+			config.DefaultTags)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -129,6 +190,36 @@ func NewJSONParser(
 	return parser, nil
 }
 
+func newJSONParser(
+	metricName string,
+	tagKeys []string,
+	query string,
+	stringFields []string,
+	nameKey string,
+	timeKey string,
+	timeFormat string,
+	defaultTags map[string]string,
+) (Parser, error) {
+	if timeKey != "" && timeFormat == "" {
+		return nil, fmt.Errorf("json_time_format is required with json_time_key")
+	}
+	if _, err := filter.Compile(stringFields); err != nil {
+		return nil, fmt.Errorf("invalid json_string_fields: %s", err)
+	}
+
+	parser := &json.JSONParser{
+		MetricName:   metricName,
+		TagKeys:      tagKeys,
+		DefaultTags:  defaultTags,
+		Query:        query,
+		StringFields: stringFields,
+		NameKey:      nameKey,
+		TimeKey:      timeKey,
+		TimeFormat:   timeFormat,
+	}
+	return parser, nil
+}
+
 func NewNagiosParser() (Parser, error) {
 	return &nagios.NagiosParser{}, nil
 }
@@ -157,6 +248,10 @@ func NewValueParser(
 	}, nil
 }
 
+func NewPrometheusParser(defaultTags map[string]string) (Parser, error) {
+	return &prometheus.Parser{DefaultTags: defaultTags}, nil
+}
+
 func NewCollectdParser(
 	authFile string,
 	securityLevel string,
@@ -190,3 +285,54 @@ func NewDropwizardParser(
 
 	return parser, err
 }
+
+func newCSVParser(metricName string,
+	headerRowCount int,
+	skipRows int,
+	skipColumns int,
+	delimiter string,
+	comment string,
+	trimSpace bool,
+	columnNames []string,
+	columnTypes []string,
+	tagColumns []string,
+	measurementColumn string,
+	timestampColumn string,
+	timestampFormat string,
+	defaultTags map[string]string,
+) (Parser, error) {
+	if headerRowCount == 0 && len(columnNames) == 0 {
+		return nil, fmt.Errorf("csv_column_names are required without csv_header_row_count")
+	}
+	if len(columnNames) > 0 && len(columnTypes) > 0 && len(columnNames) != len(columnTypes) {
+		return nil, fmt.Errorf("csv_column_names and csv_column_types must have the same length")
+	}
+	for _, typ := range columnTypes {
+		switch typ {
+		case "int", "float", "bool", "string":
+		default:
+			return nil, fmt.Errorf("csv_column_types: unknown type %q", typ)
+		}
+	}
+	if timestampColumn != "" && timestampFormat == "" {
+		return nil, fmt.Errorf("csv_timestamp_format is required with csv_timestamp_column")
+	}
+
+	parser := &csv.Parser{
+		MetricName:        metricName,
+		HeaderRowCount:    headerRowCount,
+		SkipRows:          skipRows,
+		SkipColumns:       skipColumns,
+		Delimiter:         delimiter,
+		Comment:           comment,
+		TrimSpace:         trimSpace,
+		ColumnNames:       columnNames,
+		ColumnTypes:       columnTypes,
+		TagColumns:        tagColumns,
+		MeasurementColumn: measurementColumn,
+		TimestampColumn:   timestampColumn,
+		TimestampFormat:   timestampFormat,
+		DefaultTags:       defaultTags,
+	}
+	return parser, nil
+}
@@ -0,0 +1,410 @@
+package converter
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tags to convert
+  ##
+  ## The table key determines the target type, and the array of key-values
+  ## select the keys to convert.  The array may contain globs.
+  ##   <target-type> = [<tag-key>...]
+  [processors.converter.tags]
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+
+  ## Fields to convert
+  ##
+  ## The table key determines the target type, and the array of key-values
+  ## select the keys to convert.  The array may contain globs.
+  ##   <target-type> = [<field-key>...]
+  [processors.converter.fields]
+    tag = []
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+`
+
+type Conversion struct {
+	Tag      []string
+	String   []string
+	Integer  []string
+	Unsigned []string
+	Boolean  []string
+	Float    []string
+}
+
+type Converter struct {
+	Tags   *Conversion
+	Fields *Conversion
+
+	initialized     bool
+	tagConversions  *ConversionFilter
+	fieldConversion *ConversionFilter
+}
+
+type ConversionFilter struct {
+	Tag      filter.Filter
+	String   filter.Filter
+	Integer  filter.Filter
+	Unsigned filter.Filter
+	Boolean  filter.Filter
+	Float    filter.Filter
+}
+
+func (p *Converter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Converter) Description() string {
+	return "Convert values to another metric value type"
+}
+
+func (p *Converter) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	// An invalid configuration is only reported once, the metrics are
+	// passed on unchanged then.
+	if !p.initialized {
+		err := p.compile()
+		if err != nil {
+			log.Printf("E! [processors.converter] %s", err)
+		}
+		p.initialized = true
+	}
+
+	for _, metric := range metrics {
+		p.convertTags(metric)
+		p.convertFields(metric)
+	}
+	return metrics
+}
+
+func (p *Converter) compile() error {
+	tf, err := compileFilter(p.Tags)
+	if err != nil {
+		return err
+	}
+
+	ff, err := compileFilter(p.Fields)
+	if err != nil {
+		return err
+	}
+
+	if tf == nil && ff == nil {
+		return fmt.Errorf("no filters found")
+	}
+
+	p.tagConversions = tf
+	p.fieldConversion = ff
+	return nil
+}
+
+func compileFilter(conv *Conversion) (*ConversionFilter, error) {
+	if conv == nil {
+		return nil, nil
+	}
+
+	var err error
+	cf := &ConversionFilter{}
+	cf.Tag, err = filter.Compile(conv.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	cf.String, err = filter.Compile(conv.String)
+	if err != nil {
+		return nil, err
+	}
+
+	cf.Integer, err = filter.Compile(conv.Integer)
+	if err != nil {
+		return nil, err
+	}
+
+	cf.Unsigned, err = filter.Compile(conv.Unsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	cf.Boolean, err = filter.Compile(conv.Boolean)
+	if err != nil {
+		return nil, err
+	}
+
+	cf.Float, err = filter.Compile(conv.Float)
+	if err != nil {
+		return nil, err
+	}
+
+	return cf, nil
+}
+
+// convertTags converts tags into fields.  A tag that cannot be converted is
+// removed, so that it never ends up with the wrong type.
+func (p *Converter) convertTags(metric telegraf.Metric) {
+	if p.tagConversions == nil {
+		return
+	}
+
+	for key, value := range metric.Tags() {
+		var (
+			converted interface{}
+			ok        bool
+			target    string
+		)
+		switch {
+		case match(p.tagConversions.String, key):
+			converted, ok, target = value, true, "string"
+		case match(p.tagConversions.Integer, key):
+			converted, ok = toInteger(value)
+			target = "integer"
+		case match(p.tagConversions.Unsigned, key):
+			converted, ok = toUnsigned(value)
+			target = "unsigned"
+		case match(p.tagConversions.Boolean, key):
+			converted, ok = toBool(value)
+			target = "boolean"
+		case match(p.tagConversions.Float, key):
+			converted, ok = toFloat(value)
+			target = "float"
+		default:
+			continue
+		}
+
+		metric.RemoveTag(key)
+		if !ok {
+			logConversionError(metric, "tag", key, value, target)
+			continue
+		}
+		metric.AddField(key, converted)
+	}
+}
+
+// convertFields converts fields into tags or other field types.  A field
+// that cannot be converted is removed, so that it never ends up with the
+// wrong type.
+func (p *Converter) convertFields(metric telegraf.Metric) {
+	if p.fieldConversion == nil {
+		return
+	}
+
+	for key, value := range metric.Fields() {
+		var (
+			converted interface{}
+			ok        bool
+			target    string
+		)
+		switch {
+		case match(p.fieldConversion.Tag, key):
+			v, ok := toString(value)
+			if !ok {
+				logConversionError(metric, "field", key, value, "tag")
+				metric.RemoveField(key)
+				continue
+			}
+			// A metric cannot be left without fields, so the last field
+			// is kept and not converted.
+			if err := metric.RemoveField(key); err != nil {
+				log.Printf("D! [processors.converter] Not converting the "+
+					"only field %q of %q to a tag", key, metric.Name())
+				continue
+			}
+			metric.AddTag(key, v)
+			continue
+		case match(p.fieldConversion.String, key):
+			converted, ok = toString(value)
+			target = "string"
+		case match(p.fieldConversion.Integer, key):
+			converted, ok = toInteger(value)
+			target = "integer"
+		case match(p.fieldConversion.Unsigned, key):
+			converted, ok = toUnsigned(value)
+			target = "unsigned"
+		case match(p.fieldConversion.Boolean, key):
+			converted, ok = toBool(value)
+			target = "boolean"
+		case match(p.fieldConversion.Float, key):
+			converted, ok = toFloat(value)
+			target = "float"
+		default:
+			continue
+		}
+
+		if !ok {
+			logConversionError(metric, "field", key, value, target)
+			metric.RemoveField(key)
+			continue
+		}
+		metric.AddField(key, converted)
+	}
+}
+
+func match(f filter.Filter, key string) bool {
+	return f != nil && f.Match(key)
+}
+
+func logConversionError(
+	metric telegraf.Metric,
+	kind, key string,
+	value interface{},
+	target string,
+) {
+	log.Printf("D! [processors.converter] Removing %s %q of %q: "+
+		"cannot convert %T %v to %s", kind, key, metric.Name(), value, value,
+		target)
+}
+
+func toBool(v interface{}) (bool, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value != 0, true
+	case uint64:
+		return value != 0, true
+	case float64:
+		return value != 0, true
+	case bool:
+		return value, true
+	case string:
+		result, err := strconv.ParseBool(value)
+		return result, err == nil
+	}
+	return false, false
+}
+
+// round rounds v to the nearest integer, rounding half away from zero.
+func round(v float64) float64 {
+	if v < 0 {
+		return math.Ceil(v - 0.5)
+	}
+	return math.Floor(v + 0.5)
+}
+
+func toInteger(v interface{}) (int64, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value, true
+	case uint64:
+		if value <= uint64(math.MaxInt64) {
+			return int64(value), true
+		}
+		return math.MaxInt64, true
+	case float64:
+		if math.IsNaN(value) {
+			return 0, false
+		} else if value < float64(math.MinInt64) {
+			return math.MinInt64, true
+		} else if value > float64(math.MaxInt64) {
+			return math.MaxInt64, true
+		} else {
+			return int64(round(value)), true
+		}
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		result, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			f, ok := toFloat(value)
+			if !ok {
+				return 0, false
+			}
+			return toInteger(f)
+		}
+		return result, true
+	}
+	return 0, false
+}
+
+// toUnsigned converts v to an unsigned integer.  Negative values cannot be
+// converted.
+func toUnsigned(v interface{}) (uint64, bool) {
+	switch value := v.(type) {
+	case int64:
+		if value < 0 {
+			return 0, false
+		}
+		return uint64(value), true
+	case uint64:
+		return value, true
+	case float64:
+		if value < 0.0 || math.IsNaN(value) {
+			return 0, false
+		} else if value > float64(math.MaxUint64) {
+			return math.MaxUint64, true
+		} else {
+			return uint64(round(value)), true
+		}
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		result, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			f, ok := toFloat(value)
+			if !ok {
+				return 0, false
+			}
+			return toUnsigned(f)
+		}
+		return result, true
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1.0, true
+		}
+		return 0.0, true
+	case string:
+		result, err := strconv.ParseFloat(value, 64)
+		return result, err == nil
+	}
+	return 0.0, false
+}
+
+func toString(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case uint64:
+		return strconv.FormatUint(value, 10), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case string:
+		return value, true
+	}
+	return "", false
+}
+
+func init() {
+	processors.Add("converter", func() telegraf.Processor {
+		return &Converter{}
+	})
+}
@@ -0,0 +1,235 @@
+package converter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Metric(v telegraf.Metric, err error) telegraf.Metric {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestConverter(t *testing.T) {
+	tests := []struct {
+		name      string
+		converter *Converter
+		input     telegraf.Metric
+		expected  telegraf.Metric
+	}{
+		{
+			name:      "empty",
+			converter: &Converter{},
+			input: Metric(metric.New("cpu",
+				map[string]string{"host": "localhost"},
+				map[string]interface{}{"value": 42.0},
+				time.Unix(0, 0))),
+			expected: Metric(metric.New("cpu",
+				map[string]string{"host": "localhost"},
+				map[string]interface{}{"value": 42.0},
+				time.Unix(0, 0))),
+		},
+		{
+			name: "from tag",
+			converter: &Converter{
+				Tags: &Conversion{
+					String:   []string{"string"},
+					Integer:  []string{"int"},
+					Unsigned: []string{"uint"},
+					Boolean:  []string{"bool"},
+					Float:    []string{"float"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{
+					"host":   "localhost",
+					"string": "howdy",
+					"int":    "42",
+					"uint":   "43",
+					"bool":   "true",
+					"float":  "4.2",
+				},
+				map[string]interface{}{"value": 42.0},
+				time.Unix(0, 0))),
+			expected: Metric(metric.New("cpu",
+				map[string]string{"host": "localhost"},
+				map[string]interface{}{
+					"value":  42.0,
+					"string": "howdy",
+					"int":    int64(42),
+					"uint":   int64(43),
+					"bool":   true,
+					"float":  4.2,
+				},
+				time.Unix(0, 0))),
+		},
+		{
+			name: "from tag unconvertible is removed",
+			converter: &Converter{
+				Tags: &Conversion{
+					Integer:  []string{"int"},
+					Unsigned: []string{"uint"},
+					Boolean:  []string{"bool"},
+					Float:    []string{"float"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{
+					"int":   "a",
+					"uint":  "-1",
+					"bool":  "maybe",
+					"float": "x",
+				},
+				map[string]interface{}{"value": 42.0},
+				time.Unix(0, 0))),
+			expected: Metric(metric.New("cpu",
+				map[string]string{},
+				map[string]interface{}{"value": 42.0},
+				time.Unix(0, 0))),
+		},
+		{
+			name: "from string field",
+			converter: &Converter{
+				Fields: &Conversion{
+					Integer:  []string{"a", "b"},
+					Unsigned: []string{"c"},
+					Boolean:  []string{"d", "e"},
+					Float:    []string{"f"},
+					Tag:      []string{"g"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"a": "42",
+					"b": "42.6",
+					"c": "0x2a",
+					"d": "true",
+					"e": "nope",
+					"f": "4.2",
+					"g": "localhost",
+				},
+				time.Unix(0, 0))),
+			expected: Metric(metric.New("cpu",
+				map[string]string{"g": "localhost"},
+				map[string]interface{}{
+					"a": int64(42),
+					"b": int64(43),
+					"c": int64(42),
+					"d": true,
+					"f": 4.2,
+				},
+				time.Unix(0, 0))),
+		},
+		{
+			name: "from numeric and bool fields",
+			converter: &Converter{
+				Fields: &Conversion{
+					String:  []string{"a", "b*"},
+					Integer: []string{"c", "d"},
+					Float:   []string{"e"},
+					Tag:     []string{"f"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"a":  int64(42),
+					"b1": 4.2,
+					"b2": true,
+					"c":  true,
+					"d":  math.MaxFloat64,
+					"e":  int64(42),
+					"f":  int64(7),
+				},
+				time.Unix(0, 0))),
+			expected: Metric(metric.New("cpu",
+				map[string]string{"f": "7"},
+				map[string]interface{}{
+					"a":  "42",
+					"b1": "4.2",
+					"b2": "true",
+					"c":  int64(1),
+					"d":  int64(math.MaxInt64),
+					"e":  42.0,
+				},
+				time.Unix(0, 0))),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := tt.converter.Apply(tt.input)
+
+			require.Len(t, metrics, 1)
+			assert.Equal(t, tt.expected.Name(), metrics[0].Name())
+			assert.Equal(t, tt.expected.Tags(), metrics[0].Tags())
+			assert.Equal(t, tt.expected.Fields(), metrics[0].Fields())
+			assert.Equal(t, tt.expected.Time(), metrics[0].Time())
+		})
+	}
+}
+
+func TestConvertOnlyField(t *testing.T) {
+	converter := &Converter{
+		Fields: &Conversion{
+			Integer: []string{"value"},
+		},
+	}
+	m := Metric(metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": "42"},
+		time.Unix(0, 0)))
+
+	metrics := converter.Apply(m)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"value": int64(42)}, metrics[0].Fields())
+}
+
+func TestConvertOnlyFieldToTag(t *testing.T) {
+	converter := &Converter{
+		Fields: &Conversion{
+			Tag: []string{"value"},
+		},
+	}
+	m := Metric(metric.New("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": "42"},
+		time.Unix(0, 0)))
+
+	metrics := converter.Apply(m)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]string{"host": "localhost"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": "42"}, metrics[0].Fields())
+}
+
+func TestRound(t *testing.T) {
+	v, ok := toInteger(2.5)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), v)
+	v, ok = toInteger(-2.5)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-3), v)
+	v, ok = toInteger(-2.4)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-2), v)
+	u, ok := toUnsigned(2.5)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), u)
+}
+
+func TestToUnsigned(t *testing.T) {
+	v, ok := toUnsigned(int64(-1))
+	assert.False(t, ok)
+	v, ok = toUnsigned("18446744073709551615")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(math.MaxUint64), v)
+}
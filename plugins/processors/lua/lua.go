@@ -0,0 +1,323 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+
+	"github.com/yuin/gopher-lua"
+)
+
+var sampleConfig = `
+  ## Lua source of the script, it must define a function apply(metric)
+  ## which returns nil, a metric or a list of metrics.
+  source = '''
+function apply(metric)
+  return metric
+end
+'''
+
+  ## File containing the script, instead of source.
+  # script = "/etc/telegraf/apply.lua"
+
+  ## Maximum time a call of apply may take, the metric is passed on
+  ## unchanged if it takes longer.
+  # timeout = "1s"
+`
+
+// libs are the standard libraries available to scripts, the others give
+// access to files, the environment or other modules.
+var libs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// unsafeGlobals are the functions of the base library that load code.
+var unsafeGlobals = []string{
+	"dofile", "load", "loadfile", "loadstring", "module", "require",
+}
+
+type Lua struct {
+	Source  string
+	Script  string
+	Timeout internal.Duration
+
+	src     string
+	state   *lua.LState
+	applyFn *lua.LFunction
+	err     error
+
+	// origins are the metric tables of the current call of apply.
+	origins map[*lua.LTable]*origin
+}
+
+func (l *Lua) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Lua) Description() string {
+	return "Process metrics using a Lua script"
+}
+
+func (l *Lua) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		// The script is loaded on first use, as processors have no other
+		// point of initialization. It is reloaded after a timeout, as the
+		// state of the interrupted call is lost.
+		if l.state == nil && l.err == nil {
+			if l.err = l.load(); l.err != nil {
+				log.Printf("E! [processors.lua] %s, passing metrics unchanged",
+					l.err)
+			}
+		}
+		if l.err != nil {
+			out = append(out, m)
+			continue
+		}
+
+		metrics, err := l.apply(m)
+		if err != nil {
+			log.Printf("E! [processors.lua] %s", err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, metrics...)
+	}
+	return out
+}
+
+// load creates the state of the script and runs it.
+func (l *Lua) load() error {
+	if l.src == "" {
+		switch {
+		case l.Source != "" && l.Script != "":
+			return errors.New("only one of source and script may be set")
+		case l.Source != "":
+			l.src = l.Source
+		case l.Script != "":
+			b, err := ioutil.ReadFile(l.Script)
+			if err != nil {
+				return err
+			}
+			l.src = string(b)
+		default:
+			return errors.New("one of source and script must be set")
+		}
+	}
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range libs {
+		err := L.CallByParam(lua.P{
+			Fn:      L.NewFunction(lib.open),
+			NRet:    0,
+			Protect: true,
+		}, lua.LString(lib.name))
+		if err != nil {
+			L.Close()
+			return err
+		}
+	}
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	L.SetGlobal("print", L.NewFunction(printFn))
+	L.SetGlobal("Metric", L.NewFunction(l.newMetricFn))
+	L.SetGlobal("deepcopy", L.NewFunction(l.deepcopyFn))
+
+	fn, err := L.LoadString(l.src)
+	if err == nil {
+		err = l.call(L, fn)
+	}
+	if err != nil {
+		L.Close()
+		return err
+	}
+
+	applyFn, ok := L.GetGlobal("apply").(*lua.LFunction)
+	if !ok {
+		L.Close()
+		return errors.New("script does not define function apply(metric)")
+	}
+	l.state = L
+	l.applyFn = applyFn
+	return nil
+}
+
+// call calls fn with args within the timeout. The state is closed if the
+// timeout is exceeded.
+func (l *Lua) call(L *lua.LState, fn *lua.LFunction, args ...lua.LValue) error {
+	nret := 0
+	if len(args) > 0 {
+		nret = 1
+	}
+	p := lua.P{Fn: fn, NRet: nret, Protect: true}
+	if l.Timeout.Duration <= 0 {
+		return L.CallByParam(p, args...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.Timeout.Duration)
+	defer cancel()
+	L.SetContext(ctx)
+	err := L.CallByParam(p, args...)
+	L.RemoveContext()
+	if ctx.Err() == context.DeadlineExceeded {
+		L.Close()
+		if L == l.state {
+			l.state = nil
+		}
+		return fmt.Errorf("timeout of %s exceeded", l.Timeout.Duration)
+	}
+	return err
+}
+
+// apply calls the apply function of the script for m and returns the
+// resulting metrics. On error, m is left unchanged.
+func (l *Lua) apply(m telegraf.Metric) ([]telegraf.Metric, error) {
+	L := l.state
+	l.origins = make(map[*lua.LTable]*origin)
+	defer func() { l.origins = nil }()
+
+	t, o, err := newTable(L, m)
+	if err != nil {
+		return nil, err
+	}
+	l.origins[t] = o
+
+	if err := l.call(L, l.applyFn, t); err != nil {
+		return nil, err
+	}
+	rv := L.Get(-1)
+	L.Pop(1)
+
+	var returned []*lua.LTable
+	switch rv := rv.(type) {
+	case *lua.LNilType:
+	case *lua.LTable:
+		if _, ok := l.origins[rv]; ok {
+			returned = append(returned, rv)
+			break
+		}
+		for i := 1; i <= rv.Len(); i++ {
+			rt, ok := rv.RawGetInt(i).(*lua.LTable)
+			if !ok || l.origins[rt] == nil {
+				return nil, fmt.Errorf("apply returned a %s in a list, not a metric",
+					rv.RawGetInt(i).Type())
+			}
+			returned = append(returned, rt)
+		}
+	default:
+		return nil, fmt.Errorf("apply returned a %s, not a metric", rv.Type())
+	}
+
+	// Check all contents before modifying any metric, so that m is
+	// unchanged on error.
+	type contents struct {
+		name   string
+		tags   map[string]string
+		fields map[string]interface{}
+		time   int64
+	}
+	cs := make([]contents, len(returned))
+	for i, rt := range returned {
+		c := &cs[i]
+		c.name, c.tags, c.fields, c.time, err = l.origins[rt].contents(rt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var out []telegraf.Metric
+	seen := make(map[*lua.LTable]bool)
+	for i, rt := range returned {
+		ro := l.origins[rt]
+		// A metric returned several times is copied.
+		if seen[rt] {
+			ro = &origin{vtype: ro.vtype, time: ro.time, fields: ro.fields}
+		}
+		seen[rt] = true
+
+		// Metrics without fields are not valid.
+		if len(cs[i].fields) == 0 {
+			if ro.metric != nil {
+				ro.metric.Drop()
+			}
+			continue
+		}
+
+		om, err := ro.toMetric(cs[i].name, cs[i].tags, cs[i].fields, cs[i].time)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, om)
+	}
+	if !seen[t] {
+		m.Drop()
+	}
+	return out, nil
+}
+
+// printFn writes its arguments to the log.
+func printFn(L *lua.LState) int {
+	args := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		args = append(args, L.ToStringMeta(L.Get(i)).String())
+	}
+	log.Printf("I! [processors.lua] %s", strings.Join(args, "\t"))
+	return 0
+}
+
+// newMetricFn implements Metric(name), which creates a new metric with the
+// current time.
+func (l *Lua) newMetricFn(L *lua.LState) int {
+	name := L.CheckString(1)
+	if l.origins == nil {
+		L.RaiseError("Metric can only be called by apply")
+	}
+	now := time.Now().UnixNano()
+
+	t := L.NewTable()
+	t.RawSetString("name", lua.LString(name))
+	t.RawSetString("tags", L.NewTable())
+	t.RawSetString("fields", L.NewTable())
+	t.RawSetString("time", lua.LNumber(now))
+	l.origins[t] = &origin{vtype: telegraf.Untyped, time: now}
+	L.Push(t)
+	return 1
+}
+
+// deepcopyFn implements deepcopy(metric), which returns a new metric with the
+// same contents.
+func (l *Lua) deepcopyFn(L *lua.LState) int {
+	t := L.CheckTable(1)
+	o, ok := l.origins[t]
+	if !ok {
+		L.ArgError(1, "metric expected")
+	}
+
+	c := copyTable(L, t)
+	l.origins[c] = &origin{vtype: o.vtype, time: o.time, fields: o.fields}
+	L.Push(c)
+	return 1
+}
+
+func init() {
+	processors.Add("lua", func() telegraf.Processor {
+		return &Lua{
+			Timeout: internal.Duration{Duration: time.Second},
+		}
+	})
+}
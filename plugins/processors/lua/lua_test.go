@@ -0,0 +1,181 @@
+package lua
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLua(source string) *Lua {
+	return &Lua{
+		Source:  source,
+		Timeout: internal.Duration{Duration: time.Second},
+	}
+}
+
+func createTestMetric() telegraf.Metric {
+	m, _ := metric.New("disk",
+		map[string]string{"host": "localhost", "path": "/"},
+		map[string]interface{}{"used": int64(25), "total": int64(100)},
+		time.Unix(0, 1519194109000000042),
+	)
+	return m
+}
+
+func TestApplyModifiesMetric(t *testing.T) {
+	l := newLua(`
+function apply(metric)
+  metric.fields.used_percent = 100 * metric.fields.used / metric.fields.total
+  metric.name = metric.name .. "_" .. metric.tags.host
+  metric.tags.host = nil
+  return metric
+end
+`)
+	out := l.Apply(createTestMetric())
+	require.Len(t, out, 1)
+	assert.Equal(t, "disk_localhost", out[0].Name())
+	assert.Equal(t, map[string]string{"path": "/"}, out[0].Tags())
+	assert.Equal(t, 25.0, out[0].Fields()["used_percent"])
+	assert.Equal(t, int64(25), out[0].Fields()["used"])
+	assert.Equal(t, int64(1519194109000000042), out[0].UnixNano())
+	assert.Equal(t, 1, strings.Count(out[0].String(), "used="))
+}
+
+func TestApplyReplacesFields(t *testing.T) {
+	l := newLua(`
+function apply(metric)
+  metric.fields = {free = metric.fields.total - metric.fields.used, used = 0}
+  return metric
+end
+`)
+	out := l.Apply(createTestMetric())
+	require.Len(t, out, 1)
+	assert.Equal(t,
+		map[string]interface{}{"free": 75.0, "used": int64(0)},
+		out[0].Fields())
+	assert.Equal(t, 1, strings.Count(out[0].String(), "used="))
+}
+
+func TestApplyDropsMetric(t *testing.T) {
+	l := newLua(`
+function apply(metric)
+  if metric.tags.path == "/" then
+    return nil
+  end
+  return metric
+end
+`)
+	assert.Empty(t, l.Apply(createTestMetric()))
+}
+
+func TestApplyEmitsMetrics(t *testing.T) {
+	l := newLua(`
+function apply(metric)
+  local free = Metric("disk_free")
+  free.fields.value = metric.fields.total - metric.fields.used
+  free.time = 1000
+  local copy = deepcopy(metric)
+  copy.name = "disk_copy"
+  return {metric, free, copy}
+end
+`)
+	out := l.Apply(createTestMetric())
+	require.Len(t, out, 3)
+	assert.Equal(t, "disk", out[0].Name())
+	assert.Equal(t, "disk_free", out[1].Name())
+	assert.Equal(t, map[string]interface{}{"value": 75.0}, out[1].Fields())
+	assert.Equal(t, int64(1000), out[1].UnixNano())
+	assert.Equal(t, "disk_copy", out[2].Name())
+	assert.Equal(t, out[0].Tags(), out[2].Tags())
+	assert.Equal(t, out[0].Fields(), out[2].Fields())
+	assert.Equal(t, out[0].UnixNano(), out[2].UnixNano())
+}
+
+func TestApplyErrorPassesMetric(t *testing.T) {
+	l := newLua(`
+function apply(metric)
+  metric.name = "changed"
+  metric.tags.bad = 42
+  return metric
+end
+`)
+	out := l.Apply(createTestMetric())
+	require.Len(t, out, 1)
+	assert.Equal(t, "disk", out[0].Name())
+	assert.False(t, out[0].HasTag("bad"))
+}
+
+func TestApplyTimeout(t *testing.T) {
+	l := newLua(`
+function apply(metric)
+  if metric.tags.path == "/" then
+    while true do end
+  end
+  return nil
+end
+`)
+	l.Timeout.Duration = 10 * time.Millisecond
+	out := l.Apply(createTestMetric())
+	require.Len(t, out, 1)
+	assert.Equal(t, "disk", out[0].Name())
+
+	// The script is reloaded after the timeout.
+	m := createTestMetric()
+	m.RemoveTag("path")
+	assert.Empty(t, l.Apply(m))
+}
+
+func TestSandbox(t *testing.T) {
+	for _, name := range []string{
+		"dofile", "load", "loadfile", "loadstring",
+		"require", "module", "os", "io", "debug", "package",
+	} {
+		l := newLua(`
+function apply(metric)
+  metric.fields.available = ` + name + ` ~= nil
+  return metric
+end
+`)
+		out := l.Apply(createTestMetric())
+		require.Len(t, out, 1)
+		assert.Equal(t, false, out[0].Fields()["available"], name)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, l := range []*Lua{
+		newLua(""),
+		newLua("x = 1"),
+		newLua("apply = 1"),
+		newLua("function apply("),
+		newLua(`m = Metric("x")`),
+		{Source: "function apply(m) return m end", Script: "apply.lua"},
+	} {
+		assert.Error(t, l.load())
+	}
+}
+
+func TestApplyTracking(t *testing.T) {
+	var delivered []telegraf.DeliveryInfo
+	notify := func(info telegraf.DeliveryInfo) {
+		delivered = append(delivered, info)
+	}
+
+	l := newLua(`
+function apply(metric)
+  return nil
+end
+`)
+	group, _ := metric.WithGroupTracking(
+		[]telegraf.Metric{createTestMetric()}, notify)
+	assert.Empty(t, l.Apply(group...))
+	require.Len(t, delivered, 1)
+	assert.True(t, delivered[0].Delivered())
+}
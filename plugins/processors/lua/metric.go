@@ -0,0 +1,241 @@
+package lua
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// origin records where a metric table passed to or created by the script
+// comes from. Metric tables are plain Lua tables, they are converted back to
+// a telegraf.Metric once the script has returned.
+type origin struct {
+	// metric is the metric the table was created from, nil for tables
+	// created by Metric and deepcopy.
+	metric telegraf.Metric
+	vtype  telegraf.ValueType
+	time   int64
+	// fields are the original fields, as Lua has a single number type.
+	fields map[string]interface{}
+}
+
+// newTable returns the metric table of m.
+func newTable(L *lua.LState, m telegraf.Metric) (*lua.LTable, *origin, error) {
+	o := &origin{
+		metric: m,
+		vtype:  m.Type(),
+		time:   m.UnixNano(),
+		fields: m.Fields(),
+	}
+
+	tags := L.NewTable()
+	for k, v := range m.Tags() {
+		tags.RawSetString(k, lua.LString(v))
+	}
+	fields := L.NewTable()
+	for k, v := range o.fields {
+		lv, err := toLua(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %q: %s", k, err)
+		}
+		fields.RawSetString(k, lv)
+	}
+
+	t := L.NewTable()
+	t.RawSetString("name", lua.LString(m.Name()))
+	t.RawSetString("tags", tags)
+	t.RawSetString("fields", fields)
+	t.RawSetString("time", lua.LNumber(o.time))
+	return t, o, nil
+}
+
+// copyTable returns a copy of the metric table t, its tags and fields are
+// copied as well.
+func copyTable(L *lua.LState, t *lua.LTable) *lua.LTable {
+	c := L.NewTable()
+	t.ForEach(func(k, v lua.LValue) {
+		if vt, ok := v.(*lua.LTable); ok {
+			vc := L.NewTable()
+			vt.ForEach(vc.RawSet)
+			v = vc
+		}
+		c.RawSet(k, v)
+	})
+	return c
+}
+
+// contents returns the name, tags, fields and time of the metric table t.
+func (o *origin) contents(t *lua.LTable) (
+	string,
+	map[string]string,
+	map[string]interface{},
+	int64,
+	error,
+) {
+	name, ok := t.RawGetString("name").(lua.LString)
+	if !ok {
+		return "", nil, nil, 0, errors.New("metric name must be a string")
+	}
+
+	tt, ok := t.RawGetString("tags").(*lua.LTable)
+	if !ok {
+		return "", nil, nil, 0, errors.New("metric tags must be a table")
+	}
+	tags := make(map[string]string)
+	var err error
+	tt.ForEach(func(k, v lua.LValue) {
+		ks, kok := k.(lua.LString)
+		vs, vok := v.(lua.LString)
+		if err != nil {
+			return
+		}
+		if !kok || !vok {
+			err = fmt.Errorf("tag %s must be a string, not a %s", k, v.Type())
+			return
+		}
+		tags[string(ks)] = string(vs)
+	})
+	if err != nil {
+		return "", nil, nil, 0, err
+	}
+
+	ft, ok := t.RawGetString("fields").(*lua.LTable)
+	if !ok {
+		return "", nil, nil, 0, errors.New("metric fields must be a table")
+	}
+	fields := make(map[string]interface{})
+	ft.ForEach(func(k, v lua.LValue) {
+		ks, kok := k.(lua.LString)
+		if err != nil {
+			return
+		}
+		if !kok {
+			err = fmt.Errorf("field key %s must be a string", k)
+			return
+		}
+		var fv interface{}
+		if fv, err = o.fromLua(string(ks), v); err != nil {
+			err = fmt.Errorf("field %q: %s", ks, err)
+			return
+		}
+		fields[string(ks)] = fv
+	})
+	if err != nil {
+		return "", nil, nil, 0, err
+	}
+
+	tn, ok := t.RawGetString("time").(lua.LNumber)
+	if !ok {
+		return "", nil, nil, 0, errors.New("metric time must be a number")
+	}
+	// The time is kept exactly if the script did not change it, nanosecond
+	// timestamps do not fit a Lua number.
+	ts := o.time
+	if tn != lua.LNumber(o.time) {
+		ts = int64(tn)
+	}
+	return string(name), tags, fields, ts, nil
+}
+
+// toMetric returns the metric with the given contents. The metric the table
+// was created from is updated in place, unless its time has changed: then it
+// is dropped and replaced by a new metric.
+func (o *origin) toMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	ts int64,
+) (telegraf.Metric, error) {
+	if o.metric == nil || o.metric.UnixNano() != ts {
+		out, err := metric.New(name, tags, fields, time.Unix(0, ts), o.vtype)
+		if err != nil {
+			return nil, err
+		}
+		if o.metric != nil {
+			o.metric.Drop()
+		}
+		return out, nil
+	}
+
+	out := o.metric
+	out.SetName(name)
+	for k := range out.Tags() {
+		if _, ok := tags[k]; !ok {
+			out.RemoveTag(k)
+		}
+	}
+	for k, v := range tags {
+		out.AddTag(k, v)
+	}
+
+	// The fields are added before the old ones are removed, as RemoveField
+	// never removes the last remaining field.
+	old := out.Fields()
+	for _, k := range sortedKeys(fields) {
+		if v, ok := old[k]; !ok || v != fields[k] {
+			out.AddField(k, fields[k])
+		}
+	}
+	for _, k := range sortedKeys(old) {
+		if _, ok := fields[k]; !ok {
+			out.RemoveField(k)
+		}
+	}
+	return out, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toLua(v interface{}) (lua.LValue, error) {
+	switch v := v.(type) {
+	case float64:
+		return lua.LNumber(v), nil
+	case int64:
+		return lua.LNumber(v), nil
+	case string:
+		return lua.LString(v), nil
+	case bool:
+		return lua.LBool(v), nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
+
+// fromLua converts the value of the field key. Numbers are integers if the
+// field held an integer and the number is still whole, floats otherwise.
+func (o *origin) fromLua(key string, v lua.LValue) (interface{}, error) {
+	switch v := v.(type) {
+	case lua.LNumber:
+		f := float64(v)
+		i, ok := o.fields[key].(int64)
+		if !ok {
+			return f, nil
+		}
+		// Integers that do not fit a Lua number are kept if unchanged.
+		if v == lua.LNumber(i) {
+			return i, nil
+		}
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), nil
+		}
+		return f, nil
+	case lua.LString:
+		return string(v), nil
+	case lua.LBool:
+		return bool(v), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", v.Type())
+}
@@ -0,0 +1,131 @@
+package regex
+
+import (
+	"log"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+type Regex struct {
+	Tags        []converter
+	Fields      []converter
+	Measurement []converter
+
+	regexCache map[string]*regexp.Regexp
+}
+
+type converter struct {
+	Key         string
+	Pattern     string
+	Replacement string
+	ResultKey   string
+}
+
+var sampleConfig = `
+  ## Replacements are applied in the order they are defined: first those of
+  ## the tags, then those of the fields and then those of the measurement.
+
+  ## Tag and field conversions defined in separate sub-tables
+  # [[processors.regex.tags]]
+  #   ## Tag to change
+  #   key = "resp_code"
+  #   ## Regular expression to match on the tag value
+  #   pattern = "^(\\d)\\d\\d$"
+  #   ## Replacement for the matched part of the value, ie, "${1}xx"
+  #   replacement = "${1}xx"
+
+  # [[processors.regex.fields]]
+  #   ## Field to change, only string fields are changed
+  #   key = "request"
+  #   ## All the power of the Go regular expressions available here
+  #   ## For example, named subgroups
+  #   pattern = "^/api(?P<method>/[\\w/]+)\\S*"
+  #   replacement = "${method}"
+  #   ## If result_key is present, a new field will be created
+  #   ## instead of changing existing field, but only if the pattern matches
+  #   result_key = "method"
+
+  ## Measurement name conversions
+  # [[processors.regex.measurement]]
+  #   pattern = "^(\\w+)_total$"
+  #   replacement = "${1}"
+`
+
+func (r *Regex) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Regex) Description() string {
+	return "Transforms tag and field values and measurement names with regex pattern"
+}
+
+func (r *Regex) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for _, c := range r.Tags {
+			if value, ok := metric.Tags()[c.Key]; ok {
+				if key, value, ok := r.convert(c, value); ok {
+					metric.AddTag(key, value)
+				}
+			}
+		}
+
+		for _, c := range r.Fields {
+			if value, ok := metric.Fields()[c.Key]; ok {
+				if s, ok := value.(string); ok {
+					if key, value, ok := r.convert(c, s); ok {
+						metric.AddField(key, value)
+					}
+				}
+			}
+		}
+
+		for _, c := range r.Measurement {
+			if _, name, ok := r.convert(c, metric.Name()); ok {
+				metric.SetName(name)
+			}
+		}
+	}
+	return in
+}
+
+// convert applies c to value and returns the key to store the result in. ok
+// is false if nothing is to be stored: when the pattern is invalid or when a
+// result key is set and the pattern does not match.
+func (r *Regex) convert(c converter, value string) (string, string, bool) {
+	regex, compiled := r.regexCache[c.Pattern]
+	if !compiled {
+		var err error
+		if regex, err = regexp.Compile(c.Pattern); err != nil {
+			log.Printf("E! [processors.regex] Invalid pattern %q: %s",
+				c.Pattern, err)
+		}
+		// An invalid pattern is cached as nil, so that it is only reported
+		// once.
+		r.regexCache[c.Pattern] = regex
+	}
+	if regex == nil {
+		return "", "", false
+	}
+
+	if c.ResultKey == "" {
+		return c.Key, regex.ReplaceAllString(value, c.Replacement), true
+	}
+	if !regex.MatchString(value) {
+		return "", "", false
+	}
+	return c.ResultKey, regex.ReplaceAllString(value, c.Replacement), true
+}
+
+func NewRegex() *Regex {
+	return &Regex{
+		regexCache: make(map[string]*regexp.Regexp),
+	}
+}
+
+func init() {
+	processors.Add("regex", func() telegraf.Processor {
+		return NewRegex()
+	})
+}
@@ -0,0 +1,83 @@
+package rename
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Renames are applied in the order they are defined. Each replacement
+  ## sets exactly one of measurement, tag or field, and dest.
+
+  # [[processors.rename.replace]]
+  #   measurement = "network_interface_throughput"
+  #   dest = "throughput"
+
+  # [[processors.rename.replace]]
+  #   tag = "hostname"
+  #   dest = "host"
+
+  # [[processors.rename.replace]]
+  #   field = "lower"
+  #   dest = "min"
+`
+
+type Replace struct {
+	Measurement string
+	Tag         string
+	Field       string
+	Dest        string
+}
+
+type Rename struct {
+	Replaces []Replace `toml:"replace"`
+}
+
+func (r *Rename) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rename) Description() string {
+	return "Rename measurements, tags, and fields that pass through this filter."
+}
+
+func (r *Rename) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, point := range in {
+		for _, replace := range r.Replaces {
+			if replace.Dest == "" {
+				continue
+			}
+
+			if replace.Measurement != "" {
+				if point.Name() == replace.Measurement {
+					point.SetName(replace.Dest)
+				}
+				continue
+			}
+
+			if replace.Tag != "" {
+				if value, ok := point.Tags()[replace.Tag]; ok {
+					point.RemoveTag(replace.Tag)
+					point.AddTag(replace.Dest, value)
+				}
+				continue
+			}
+
+			if replace.Field != "" {
+				if value, ok := point.Fields()[replace.Field]; ok {
+					// The field is added before the old one is removed, as
+					// a metric cannot be left without fields.
+					point.AddField(replace.Dest, value)
+					point.RemoveField(replace.Field)
+				}
+			}
+		}
+	}
+	return in
+}
+
+func init() {
+	processors.Add("rename", func() telegraf.Processor {
+		return &Rename{}
+	})
+}
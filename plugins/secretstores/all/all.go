@@ -0,0 +1,7 @@
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/secretstores/directory"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/exec"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/file"
+)
@@ -0,0 +1,51 @@
+package directory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+type Directory struct {
+	Directory string
+}
+
+var sampleConfig = `
+  ## Directory holding one file per secret, the name of the file being the
+  ## key of the secret. Docker and Kubernetes mount secrets like this.
+  directory = "/run/secrets"
+`
+
+func (d *Directory) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Directory) Description() string {
+	return "Read secrets from the files of a directory"
+}
+
+func (d *Directory) Get(key string) (string, error) {
+	if key == "" || key == "." || key == ".." ||
+		strings.ContainsAny(key, `/\`) {
+		return "", fmt.Errorf("invalid secret key %q", key)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(d.Directory, key))
+	if err != nil {
+		return "", err
+	}
+	// Most tools that create secret files terminate them with a newline.
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("directory", func() telegraf.SecretStore {
+		return &Directory{
+			Directory: "/run/secrets",
+		}
+	})
+}
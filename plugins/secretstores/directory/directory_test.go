@@ -0,0 +1,36 @@
+package directory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(dir, "password"), []byte("hunter2\n"), 0600))
+
+	d := &Directory{Directory: dir}
+	value, err := d.Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	_, err = d.Get("missing")
+	assert.Error(t, err)
+}
+
+func TestGetInvalidKey(t *testing.T) {
+	d := &Directory{Directory: "/run/secrets"}
+	for _, key := range []string{"", "..", "../etc/passwd", "a/b"} {
+		_, err := d.Get(key)
+		assert.Error(t, err, key)
+	}
+}
@@ -0,0 +1,68 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+type Exec struct {
+	Command []string
+	Timeout internal.Duration
+}
+
+var sampleConfig = `
+  ## Helper command and its arguments. The key of the secret is appended as
+  ## the last argument and the helper must write the secret to stdout.
+  command = ["/usr/local/bin/get-secret"]
+
+  ## Timeout for the helper to complete.
+  timeout = "5s"
+`
+
+func (e *Exec) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Exec) Description() string {
+	return "Get secrets from the output of a helper command"
+}
+
+func (e *Exec) Get(key string) (string, error) {
+	if len(e.Command) == 0 {
+		return "", fmt.Errorf("no command configured")
+	}
+
+	args := append(e.Command[1:len(e.Command):len(e.Command)], key)
+	cmd := exec.Command(e.Command[0], args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := internal.RunTimeout(cmd, e.Timeout.Duration); err != nil {
+		// Only the first line of stderr is reported, stdout is never
+		// included as it may hold (part of) the secret.
+		msg := strings.SplitN(strings.TrimSpace(stderr.String()), "\n", 2)[0]
+		if msg != "" {
+			return "", fmt.Errorf("%s for command '%s': %s",
+				err, e.Command[0], msg)
+		}
+		return "", fmt.Errorf("%s for command '%s'", err, e.Command[0])
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("exec", func() telegraf.SecretStore {
+		return &Exec{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
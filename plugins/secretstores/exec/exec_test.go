@@ -0,0 +1,40 @@
+// +build !windows
+
+package exec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExec(command ...string) *Exec {
+	return &Exec{
+		Command: command,
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+}
+
+func TestGet(t *testing.T) {
+	e := newExec("/bin/sh", "-c", `echo "secret-of-$0"`)
+	value, err := e.Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "secret-of-password", value)
+}
+
+func TestGetFailure(t *testing.T) {
+	e := newExec("/bin/sh", "-c", `echo leaked; echo "no such key $0" >&2; exit 1`)
+	_, err := e.Get("password")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no such key password")
+	assert.NotContains(t, err.Error(), "leaked")
+}
+
+func TestGetNoCommand(t *testing.T) {
+	_, err := newExec().Get("password")
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+// KeySize is the size in bytes of the key used to encrypt a store, the store
+// is encrypted with AES-256-GCM.
+const KeySize = 32
+
+type File struct {
+	Path    string
+	KeyFile string
+
+	once    sync.Once
+	secrets map[string]string
+	err     error
+}
+
+var sampleConfig = `
+  ## Encrypted file holding the secrets, create it from a JSON object mapping
+  ## keys to secrets with:
+  ##   telegraf encrypt-secrets <key_file> < secrets.json > secrets.enc
+  path = "/etc/telegraf/secrets.enc"
+
+  ## File holding the hex encoded 256 bit key of the store, for example as
+  ## created with 'openssl rand -hex 32'.
+  key_file = "/etc/telegraf/secrets.key"
+`
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from an encrypted file"
+}
+
+func (f *File) Get(key string) (string, error) {
+	// The store is only decrypted when a secret is needed for the first
+	// time.
+	f.once.Do(func() {
+		f.secrets, f.err = f.load()
+	})
+	if f.err != nil {
+		return "", f.err
+	}
+
+	value, ok := f.secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found in %s", key, f.Path)
+	}
+	return value, nil
+}
+
+func (f *File) load() (map[string]string, error) {
+	key, err := ReadKey(f.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := Decrypt(key, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", f.Path, err)
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("%s: invalid secret store", f.Path)
+	}
+	return secrets, nil
+}
+
+// ReadKey reads the hex encoded key from the file at path.
+func ReadKey(path string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(contents)))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("%s: key must be %d hex encoded bytes",
+			path, KeySize)
+	}
+	return key, nil
+}
+
+// Encrypt encrypts plaintext with key, the result is the nonce followed by
+// the ciphertext.
+func Encrypt(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts data produced by Encrypt.
+func Decrypt(key, data []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("invalid secret store")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("unable to decrypt secret store, wrong key?")
+	}
+	return plaintext, nil
+}
+
+// EncryptSecrets reads a JSON object mapping keys to secrets from r and
+// writes it to w encrypted with the key in keyFile.
+func EncryptSecrets(keyFile string, r io.Reader, w io.Writer) error {
+	key, err := ReadKey(keyFile)
+	if err != nil {
+		return err
+	}
+	plaintext, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("secrets must be a JSON object of strings: %s", err)
+	}
+
+	data, err := Encrypt(key, plaintext)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
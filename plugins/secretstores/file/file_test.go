@@ -0,0 +1,82 @@
+package file
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func writeStore(t *testing.T, dir, secrets string) *File {
+	keyFile := filepath.Join(dir, "secrets.key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(testKey+"\n"), 0600))
+
+	var buf bytes.Buffer
+	require.NoError(t, EncryptSecrets(keyFile, strings.NewReader(secrets), &buf))
+	assert.NotContains(t, buf.String(), "hunter2")
+
+	path := filepath.Join(dir, "secrets.enc")
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0600))
+	return &File{Path: path, KeyFile: keyFile}
+}
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := writeStore(t, dir, `{"password": "hunter2"}`)
+	value, err := f.Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	_, err = f.Get("missing")
+	assert.Error(t, err)
+}
+
+func TestGetWrongKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := writeStore(t, dir, `{"password": "hunter2"}`)
+	other := strings.Repeat("ff", KeySize)
+	require.NoError(t, ioutil.WriteFile(f.KeyFile, []byte(other), 0600))
+
+	_, err = f.Get("password")
+	assert.Error(t, err)
+}
+
+func TestEncryptSecretsInvalidJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "secrets.key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(testKey), 0600))
+	err = EncryptSecrets(keyFile, strings.NewReader(`["password"]`), ioutil.Discard)
+	assert.Error(t, err)
+}
+
+func TestDecryptTampered(t *testing.T) {
+	key, err := hex.DecodeString(testKey)
+	require.NoError(t, err)
+
+	data, err := Encrypt(key, []byte("hello"))
+	require.NoError(t, err)
+	plaintext, err := Decrypt(key, data)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(plaintext))
+
+	data[len(data)-1] ^= 1
+	_, err = Decrypt(key, data)
+	assert.Error(t, err)
+}
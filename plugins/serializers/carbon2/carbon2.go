@@ -0,0 +1,112 @@
+package carbon2
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// FieldSeparate writes the field name in its own "field" intrinsic tag.
+	FieldSeparate = "field_separate"
+	// MetricIncludesField joins the field name to the measurement name in
+	// the "metric" intrinsic tag.
+	MetricIncludesField = "metric_includes_field"
+)
+
+var spaceReplacer = strings.NewReplacer(" ", "_")
+
+// Carbon2Serializer writes metrics in the Carbon 2.0 format, a line per
+// numeric field:
+//
+//	metric=cpu field=usage_idle cpu=cpu0 host=localhost  99.5 1518640000
+//
+// Intrinsic tags come first and are separated from the other tags by two
+// spaces; the timestamp is in seconds.
+type Carbon2Serializer struct {
+	// Format is FieldSeparate, the default, or MetricIncludesField.
+	Format string
+}
+
+func (s *Carbon2Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	fields := metric.Fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tags := metric.Tags()
+	tagKeys := make([]string, 0, len(tags))
+	for k := range tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+
+	timestamp := strconv.FormatInt(metric.Time().Unix(), 10)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		value, ok := formatValue(fields[k])
+		if !ok {
+			continue
+		}
+
+		buf.WriteString("metric=")
+		switch s.Format {
+		case "", FieldSeparate:
+			buf.WriteString(escape(metric.Name()))
+			buf.WriteString(" field=")
+			buf.WriteString(escape(k))
+		case MetricIncludesField:
+			buf.WriteString(escape(metric.Name() + "_" + k))
+		default:
+			return nil, fmt.Errorf("unknown carbon2 format %q", s.Format)
+		}
+		for _, tk := range tagKeys {
+			buf.WriteByte(' ')
+			buf.WriteString(escape(tk))
+			buf.WriteByte('=')
+			buf.WriteString(escape(tags[tk]))
+		}
+		buf.WriteString("  ")
+		buf.WriteString(value)
+		buf.WriteByte(' ')
+		buf.WriteString(timestamp)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// formatValue formats numbers, and booleans as 1 or 0. Other values cannot
+// be written.
+func formatValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	default:
+		return "", false
+	}
+}
+
+// escape replaces the spaces separating tags, and an empty value, which
+// carbon2 does not allow.
+func escape(s string) string {
+	if s == "" {
+		return "null"
+	}
+	return spaceReplacer.Replace(s)
+}
@@ -0,0 +1,61 @@
+package carbon2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializeFieldSeparate(t *testing.T) {
+	m, err := metric.New("cpu",
+		map[string]string{"host": "local host", "cpu": "cpu0"},
+		map[string]interface{}{
+			"usage_idle": 99.5,
+			"count":      int64(3),
+			"up":         true,
+			"state":      "ok",
+		},
+		time.Unix(1518640000, 0),
+	)
+	require.NoError(t, err)
+
+	s := Carbon2Serializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "metric=cpu field=count cpu=cpu0 host=local_host  3 1518640000\n"+
+		"metric=cpu field=up cpu=cpu0 host=local_host  1 1518640000\n"+
+		"metric=cpu field=usage_idle cpu=cpu0 host=local_host  99.5 1518640000\n",
+		string(buf))
+}
+
+func TestSerializeMetricIncludesField(t *testing.T) {
+	m, err := metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{"usage_idle": 99.5},
+		time.Unix(1518640000, 0),
+	)
+	require.NoError(t, err)
+
+	s := Carbon2Serializer{Format: MetricIncludesField}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "metric=cpu_usage_idle  99.5 1518640000\n", string(buf))
+}
+
+func TestSerializeStringsOnly(t *testing.T) {
+	m, err := metric.New("log",
+		map[string]string{},
+		map[string]interface{}{"message": "hello"},
+		time.Unix(1518640000, 0),
+	)
+	require.NoError(t, err)
+
+	s := Carbon2Serializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Empty(t, buf)
+}
@@ -12,6 +12,36 @@ type JsonSerializer struct {
 }
 
 func (s *JsonSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	m := s.createObject(metric)
+	serialized, err := ejson.Marshal(m)
+	if err != nil {
+		return []byte{}, err
+	}
+	serialized = append(serialized, '\n')
+
+	return serialized, nil
+}
+
+// SerializeBatch serializes the metrics as a single object, with the
+// metrics in its "metrics" array.
+func (s *JsonSerializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	objects := make([]interface{}, 0, len(metrics))
+	for _, metric := range metrics {
+		objects = append(objects, s.createObject(metric))
+	}
+
+	serialized, err := ejson.Marshal(map[string]interface{}{
+		"metrics": objects,
+	})
+	if err != nil {
+		return []byte{}, err
+	}
+	serialized = append(serialized, '\n')
+
+	return serialized, nil
+}
+
+func (s *JsonSerializer) createObject(metric telegraf.Metric) map[string]interface{} {
 	m := make(map[string]interface{})
 	units_nanoseconds := s.TimestampUnits.Nanoseconds()
 	// if the units passed in were less than or equal to zero,
@@ -23,11 +53,5 @@ func (s *JsonSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 	m["fields"] = metric.Fields()
 	m["name"] = metric.Name()
 	m["timestamp"] = metric.UnixNano() / units_nanoseconds
-	serialized, err := ejson.Marshal(m)
-	if err != nil {
-		return []byte{}, err
-	}
-	serialized = append(serialized, '\n')
-
-	return serialized, nil
+	return m
 }
@@ -0,0 +1,306 @@
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	invalidNameCharRE  = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	invalidLabelCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// Serializer writes metrics in the Prometheus text exposition format, the
+// way the prometheus_client output exposes them: every numeric field becomes
+// a sample named after the measurement and the field, and the measurement
+// alone for the "value" field and for the "counter" and "gauge" fields of
+// counters and gauges. Summaries and histograms, such as those read by the
+// prometheus input, are written as one summary or histogram each. Tags
+// become labels.
+type Serializer struct {
+	// ExportTimestamp adds the timestamp of the metric to its samples.
+	// Without it, Prometheus uses the time it scrapes them.
+	ExportTimestamp bool
+	// StringAsLabel turns string fields into labels. Otherwise they are
+	// left out, as are boolean fields.
+	StringAsLabel bool
+}
+
+// Serialize writes the samples of a single metric, preceded by the TYPE
+// lines of their families. As these lines are repeated for every metric,
+// only SerializeBatch yields valid exposition text for several metrics.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	c := newCollection(s)
+	c.add(metric)
+	return c.bytes(), nil
+}
+
+// SerializeBatch writes the samples of all metrics, grouped by metric
+// family.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	c := newCollection(s)
+	for _, metric := range metrics {
+		c.add(metric)
+	}
+	return c.bytes(), nil
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	name      string
+	labels    []label
+	value     float64
+	timestamp int64
+}
+
+type family struct {
+	name    string
+	typ     string
+	samples []sample
+}
+
+// collection groups the samples of metrics by metric family, since the
+// exposition format requires the samples of a family to be consecutive.
+type collection struct {
+	s        *Serializer
+	families map[string]*family
+}
+
+func newCollection(s *Serializer) *collection {
+	return &collection{
+		s:        s,
+		families: make(map[string]*family),
+	}
+}
+
+func (c *collection) family(name, typ string) *family {
+	f, ok := c.families[name]
+	if !ok {
+		f = &family{name: name, typ: typ}
+		c.families[name] = f
+	}
+	return f
+}
+
+func (c *collection) add(metric telegraf.Metric) {
+	tags := metric.Tags()
+	labels := make([]label, 0, len(tags))
+	for k, v := range tags {
+		labels = append(labels, label{sanitizeLabel(k), v})
+	}
+	fields := metric.Fields()
+	keys := make([]string, 0, len(fields))
+	values := make(map[string]float64)
+	for k, v := range fields {
+		keys = append(keys, k)
+		switch v := v.(type) {
+		case string:
+			if c.s.StringAsLabel {
+				labels = append(labels, label{sanitizeLabel(k), v})
+			}
+		default:
+			if f, ok := toFloat(v); ok {
+				values[k] = f
+			}
+		}
+	}
+	sort.Strings(keys)
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+
+	var timestamp int64
+	if c.s.ExportTimestamp {
+		timestamp = metric.UnixNano() / 1000000
+	}
+	newSample := func(name string, value float64, extra ...label) sample {
+		return sample{
+			name:      name,
+			labels:    append(append([]label(nil), labels...), extra...),
+			value:     value,
+			timestamp: timestamp,
+		}
+	}
+
+	name := sanitizeName(metric.Name())
+	switch metric.Type() {
+	case telegraf.Summary, telegraf.Histogram:
+		typ, bucketSuffix, bucketLabel := "summary", "", "quantile"
+		if metric.Type() == telegraf.Histogram {
+			typ, bucketSuffix, bucketLabel = "histogram", "_bucket", "le"
+		}
+		// A tag of the name of the bucket label is renamed, the way
+		// Prometheus renames conflicting labels when scraping.
+		for i := range labels {
+			if labels[i].name == bucketLabel {
+				labels[i].name = "exported_" + bucketLabel
+			}
+		}
+		sort.Slice(labels, func(i, j int) bool {
+			return labels[i].name < labels[j].name
+		})
+
+		var bounds []float64
+		buckets := make(map[float64]float64)
+		for k, v := range values {
+			if k == "sum" || k == "count" {
+				continue
+			}
+			bound, err := strconv.ParseFloat(k, 64)
+			if err != nil {
+				continue
+			}
+			bounds = append(bounds, bound)
+			buckets[bound] = v
+		}
+		count, hasCount := values["count"]
+		if _, ok := buckets[math.Inf(1)]; typ == "histogram" && !ok && hasCount {
+			bounds = append(bounds, math.Inf(1))
+			buckets[math.Inf(1)] = count
+		}
+		sort.Float64s(bounds)
+
+		f := c.family(name, typ)
+		for _, bound := range bounds {
+			f.samples = append(f.samples, newSample(name+bucketSuffix, buckets[bound],
+				label{bucketLabel, formatFloat(bound)}))
+		}
+		if sum, ok := values["sum"]; ok {
+			f.samples = append(f.samples, newSample(name+"_sum", sum))
+		}
+		if hasCount {
+			f.samples = append(f.samples, newSample(name+"_count", count))
+		}
+	default:
+		for _, key := range keys {
+			value, ok := values[key]
+			if !ok {
+				continue
+			}
+
+			typ := "untyped"
+			fname := ""
+			switch metric.Type() {
+			case telegraf.Counter:
+				typ = "counter"
+				if key == "counter" {
+					fname = name
+				}
+			case telegraf.Gauge:
+				typ = "gauge"
+				if key == "gauge" {
+					fname = name
+				}
+			}
+			if fname == "" {
+				if key == "value" {
+					fname = name
+				} else {
+					fname = sanitizeName(metric.Name() + "_" + key)
+				}
+			}
+
+			f := c.family(fname, typ)
+			f.samples = append(f.samples, newSample(fname, value))
+		}
+	}
+}
+
+// bytes returns the families in order of their names.
+func (c *collection) bytes() []byte {
+	names := make([]string, 0, len(c.families))
+	for name, f := range c.families {
+		if len(f.samples) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := c.families[name]
+		buf.WriteString("# TYPE ")
+		buf.WriteString(f.name)
+		buf.WriteByte(' ')
+		buf.WriteString(f.typ)
+		buf.WriteByte('\n')
+		for _, s := range f.samples {
+			buf.WriteString(s.name)
+			if len(s.labels) > 0 {
+				buf.WriteByte('{')
+				for i, l := range s.labels {
+					if i > 0 {
+						buf.WriteByte(',')
+					}
+					buf.WriteString(l.name)
+					buf.WriteString(`="`)
+					labelValueEscaper.WriteString(&buf, l.value)
+					buf.WriteByte('"')
+				}
+				buf.WriteByte('}')
+			}
+			buf.WriteByte(' ')
+			buf.WriteString(formatFloat(s.value))
+			if s.timestamp != 0 {
+				buf.WriteByte(' ')
+				buf.WriteString(strconv.FormatInt(s.timestamp, 10))
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func sanitizeName(name string) string {
+	name = invalidNameCharRE.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func sanitizeLabel(name string) string {
+	name = invalidLabelCharRE.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,173 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1518640000, 0)
+
+func TestSerializeFields(t *testing.T) {
+	m, err := metric.New("cpu",
+		map[string]string{"host": "localhost", "cpu-id": "cpu0"},
+		map[string]interface{}{
+			"usage_idle": 99.5,
+			"value":      int64(3),
+			"state":      "ok",
+			"up":         true,
+		},
+		now,
+	)
+	require.NoError(t, err)
+
+	s := Serializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE cpu untyped
+cpu{cpu_id="cpu0",host="localhost"} 3
+# TYPE cpu_usage_idle untyped
+cpu_usage_idle{cpu_id="cpu0",host="localhost"} 99.5
+`, string(buf))
+
+	s = Serializer{ExportTimestamp: true, StringAsLabel: true}
+	buf, err = s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE cpu untyped
+cpu{cpu_id="cpu0",host="localhost",state="ok"} 3 1518640000000
+# TYPE cpu_usage_idle untyped
+cpu_usage_idle{cpu_id="cpu0",host="localhost",state="ok"} 99.5 1518640000000
+`, string(buf))
+}
+
+func TestSerializeCounterAndGauge(t *testing.T) {
+	counter, err := metric.New("http_requests_total",
+		map[string]string{"path": `/a"b\c`},
+		map[string]interface{}{"counter": float64(1027)},
+		now,
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	gauge, err := metric.New("2xx.rate",
+		map[string]string{},
+		map[string]interface{}{"gauge": 0.25},
+		now,
+		telegraf.Gauge,
+	)
+	require.NoError(t, err)
+
+	s := Serializer{}
+	buf, err := s.Serialize(counter)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE http_requests_total counter
+http_requests_total{path="/a\"b\\c"} 1027
+`, string(buf))
+
+	buf, err = s.Serialize(gauge)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE _2xx_rate gauge
+_2xx_rate 0.25
+`, string(buf))
+}
+
+func TestSerializeSummary(t *testing.T) {
+	m, err := metric.New("rpc_duration_seconds",
+		map[string]string{"service": "api"},
+		map[string]interface{}{
+			"0.5":   0.05,
+			"0.99":  0.3,
+			"sum":   17.5,
+			"count": float64(200),
+		},
+		now,
+		telegraf.Summary,
+	)
+	require.NoError(t, err)
+
+	s := Serializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{service="api",quantile="0.5"} 0.05
+rpc_duration_seconds{service="api",quantile="0.99"} 0.3
+rpc_duration_seconds_sum{service="api"} 17.5
+rpc_duration_seconds_count{service="api"} 200
+`, string(buf))
+}
+
+func TestSerializeHistogram(t *testing.T) {
+	m, err := metric.New("request_latency",
+		map[string]string{},
+		map[string]interface{}{
+			"0.1":   float64(10),
+			"1":     float64(15),
+			"sum":   4.5,
+			"count": float64(16),
+		},
+		now,
+		telegraf.Histogram,
+	)
+	require.NoError(t, err)
+
+	s := Serializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE request_latency histogram
+request_latency_bucket{le="0.1"} 10
+request_latency_bucket{le="1"} 15
+request_latency_bucket{le="+Inf"} 16
+request_latency_sum 4.5
+request_latency_count 16
+`, string(buf))
+}
+
+func TestSerializeHistogramLeTag(t *testing.T) {
+	m, err := metric.New("request_latency",
+		map[string]string{"le": "x", "host": "a"},
+		map[string]interface{}{
+			"0.1":   float64(10),
+			"count": float64(16),
+		},
+		now,
+		telegraf.Histogram,
+	)
+	require.NoError(t, err)
+
+	s := Serializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE request_latency histogram
+request_latency_bucket{exported_le="x",host="a",le="0.1"} 10
+request_latency_bucket{exported_le="x",host="a",le="+Inf"} 16
+request_latency_count{exported_le="x",host="a"} 16
+`, string(buf))
+}
+
+func TestSerializeBatch(t *testing.T) {
+	var metrics []telegraf.Metric
+	for _, host := range []string{"a", "b"} {
+		m, err := metric.New("cpu",
+			map[string]string{"host": host},
+			map[string]interface{}{"usage_idle": 99.5, "usage_user": 0.5},
+			now,
+		)
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+
+	s := Serializer{}
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE cpu_usage_idle untyped
+cpu_usage_idle{host="a"} 99.5
+cpu_usage_idle{host="b"} 99.5
+# TYPE cpu_usage_user untyped
+cpu_usage_user{host="a"} 0.5
+cpu_usage_user{host="b"} 0.5
+`, string(buf))
+}
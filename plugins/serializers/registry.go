@@ -1,14 +1,18 @@
 package serializers
 
 import (
+	"bytes"
 	"fmt"
 	"time"
 
 	"github.com/influxdata/telegraf"
 
+	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
+	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
 )
 
 // SerializerOutput is an interface for output plugins that are able to
@@ -25,12 +29,42 @@ type Serializer interface {
 	// separate metrics should be separated by a newline, and there should be
 	// a newline at the end of the buffer.
 	Serialize(metric telegraf.Metric) ([]byte, error)
+
+	// SerializeBatch takes a batch of telegraf metrics and turns them into a
+	// single document, such as one JSON object holding all of them. Formats
+	// without such a document concatenate the serialized metrics.
+	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
+}
+
+// concatenated serializes a batch by concatenating the serialized metrics,
+// for the formats without a document holding several metrics.
+type concatenated struct {
+	serializer interface {
+		Serialize(metric telegraf.Metric) ([]byte, error)
+	}
+}
+
+func (c concatenated) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return c.serializer.Serialize(metric)
+}
+
+func (c concatenated) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, metric := range metrics {
+		b, err := c.serializer.Serialize(metric)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
 }
 
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
-	// Dataformat can be one of: influx, graphite, or json
+	// Dataformat can be one of: influx, graphite, json, prometheus, carbon2,
+	// or splunkmetric
 	DataFormat string
 
 	// Prefix to add to all measurements, only supports Graphite
@@ -42,6 +76,21 @@ type Config struct {
 
 	// Timestamp units to use for JSON formatted output
 	TimestampUnits time.Duration
+
+	// Whether to add the metric timestamps to Prometheus samples
+	PrometheusExportTimestamp bool
+
+	// Whether to turn string fields into Prometheus labels
+	PrometheusStringAsLabel bool
+
+	// How carbon2 names fields: field_separate or metric_includes_field
+	Carbon2Format string
+
+	// Whether to wrap splunkmetric events for the HTTP Event Collector
+	SplunkmetricHECRouting bool
+
+	// Separator joining the measurement and field names in splunkmetric
+	SplunkmetricSeparator string
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -55,6 +104,14 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewGraphiteSerializer(config.Prefix, config.Template)
 	case "json":
 		serializer, err = NewJsonSerializer(config.TimestampUnits)
+	case "prometheus":
+		serializer, err = NewPrometheusSerializer(config.PrometheusExportTimestamp,
+			config.PrometheusStringAsLabel)
+	case "carbon2":
+		serializer, err = NewCarbon2Serializer(config.Carbon2Format)
+	case "splunkmetric":
+		serializer, err = NewSplunkmetricSerializer(config.SplunkmetricHECRouting,
+			config.SplunkmetricSeparator)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -66,12 +123,36 @@ func NewJsonSerializer(timestampUnits time.Duration) (Serializer, error) {
 }
 
 func NewInfluxSerializer() (Serializer, error) {
-	return &influx.InfluxSerializer{}, nil
+	return concatenated{&influx.InfluxSerializer{}}, nil
 }
 
 func NewGraphiteSerializer(prefix, template string) (Serializer, error) {
-	return &graphite.GraphiteSerializer{
+	return concatenated{&graphite.GraphiteSerializer{
 		Prefix:   prefix,
 		Template: template,
+	}}, nil
+}
+
+func NewPrometheusSerializer(exportTimestamp, stringAsLabel bool) (Serializer, error) {
+	return &prometheus.Serializer{
+		ExportTimestamp: exportTimestamp,
+		StringAsLabel:   stringAsLabel,
 	}, nil
 }
+
+func NewCarbon2Serializer(format string) (Serializer, error) {
+	switch format {
+	case "", carbon2.FieldSeparate, carbon2.MetricIncludesField:
+	default:
+		return nil, fmt.Errorf("carbon2_format must be %q or %q, not %q",
+			carbon2.FieldSeparate, carbon2.MetricIncludesField, format)
+	}
+	return concatenated{&carbon2.Carbon2Serializer{Format: format}}, nil
+}
+
+func NewSplunkmetricSerializer(hecRouting bool, separator string) (Serializer, error) {
+	return concatenated{&splunkmetric.SplunkmetricSerializer{
+		HECRouting: hecRouting,
+		Separator:  separator,
+	}}, nil
+}
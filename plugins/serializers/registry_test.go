@@ -0,0 +1,36 @@
+package serializers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializeBatchConcatenated(t *testing.T) {
+	now := time.Now()
+	m1, err := metric.New("cpu",
+		map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": float64(91.5)},
+		now)
+	require.NoError(t, err)
+	m2, err := metric.New("mem",
+		map[string]string{},
+		map[string]interface{}{"used": int64(42)},
+		now)
+	require.NoError(t, err)
+
+	s, err := NewSerializer(&Config{DataFormat: "influx"})
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch([]telegraf.Metric{m1, m2})
+	assert.NoError(t, err)
+
+	expS := fmt.Sprintf("cpu,cpu=cpu0 usage_idle=91.5 %d\nmem used=42i %d\n",
+		now.UnixNano(), now.UnixNano())
+	assert.Equal(t, expS, string(buf))
+}
@@ -0,0 +1,102 @@
+package splunkmetric
+
+import (
+	"bytes"
+	ejson "encoding/json"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+)
+
+// SplunkmetricSerializer writes metrics as Splunk metric events, a JSON
+// object per numeric field:
+//
+//	{"_value":99.5,"cpu":"cpu0","host":"localhost","metric_name":"cpu.usage_idle","time":1518640000}
+//
+// With HECRouting, the objects are wrapped to be sent to the HTTP Event
+// Collector, which takes the host from the "host" tag:
+//
+//	{"event":"metric","fields":{"_value":99.5,"cpu":"cpu0","metric_name":"cpu.usage_idle"},"host":"localhost","time":1518640000}
+type SplunkmetricSerializer struct {
+	// HECRouting wraps the events for the HTTP Event Collector.
+	HECRouting bool
+	// Separator joins the measurement and the field name into the metric
+	// name, "." by default.
+	Separator string
+}
+
+func (s *SplunkmetricSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	separator := s.Separator
+	if separator == "" {
+		separator = "."
+	}
+
+	fields := metric.Fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Splunk takes fractional seconds.
+	timestamp := float64(metric.UnixNano()) / 1e9
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		value, ok := toFloat(fields[k])
+		if !ok {
+			continue
+		}
+
+		event := make(map[string]interface{})
+		for tk, tv := range metric.Tags() {
+			event[tk] = tv
+		}
+		event["metric_name"] = metric.Name() + separator + k
+		event["_value"] = value
+
+		var obj map[string]interface{}
+		if s.HECRouting {
+			obj = map[string]interface{}{
+				"time":   timestamp,
+				"event":  "metric",
+				"fields": event,
+			}
+			if host, ok := event["host"]; ok {
+				obj["host"] = host
+				delete(event, "host")
+			}
+		} else {
+			event["time"] = timestamp
+			obj = event
+		}
+
+		serialized, err := ejson.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(serialized)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// toFloat converts numbers, and booleans to 1 or 0, since Splunk metrics
+// are numeric.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
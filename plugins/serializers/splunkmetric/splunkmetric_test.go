@@ -0,0 +1,48 @@
+package splunkmetric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerialize(t *testing.T) {
+	m, err := metric.New("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{
+			"usage_idle": 99.5,
+			"count":      int64(3),
+			"state":      "ok",
+		},
+		time.Unix(1518640000, 500000000),
+	)
+	require.NoError(t, err)
+
+	s := SplunkmetricSerializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"_value":3,"cpu":"cpu0","host":"localhost","metric_name":"cpu.count","time":1518640000.5}`+"\n"+
+			`{"_value":99.5,"cpu":"cpu0","host":"localhost","metric_name":"cpu.usage_idle","time":1518640000.5}`+"\n",
+		string(buf))
+}
+
+func TestSerializeHECRouting(t *testing.T) {
+	m, err := metric.New("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 99.5},
+		time.Unix(1518640000, 0),
+	)
+	require.NoError(t, err)
+
+	s := SplunkmetricSerializer{HECRouting: true, Separator: "_"}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"event":"metric","fields":{"_value":99.5,"cpu":"cpu0","metric_name":"cpu_usage_idle"},"host":"localhost","time":1518640000}`+"\n",
+		string(buf))
+}
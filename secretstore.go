@@ -0,0 +1,17 @@
+package telegraf
+
+// SecretStore is an interface for implementing a SecretStore plugin, which
+// provides the values of secrets referenced from the configuration as
+// @{id:key}. Secrets are only looked up for the plugins that are loaded. Get
+// may be called concurrently, when plugins use their secret options.
+type SecretStore interface {
+	// SampleConfig returns the default configuration of the SecretStore.
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the SecretStore.
+	Description() string
+
+	// Get returns the value of the secret with the given key. The error must
+	// not contain the value of any secret.
+	Get(key string) (string, error)
+}
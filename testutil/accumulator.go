@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -36,6 +37,8 @@ type Accumulator struct {
 	Discard  bool
 	Errors   []error
 	debug    bool
+
+	delivered chan telegraf.DeliveryInfo
 }
 
 func (a *Accumulator) NMetrics() uint64 {
@@ -153,6 +156,28 @@ func (a *Accumulator) AddError(err error) {
 	a.Unlock()
 }
 
+func (a *Accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	a.delivered = make(chan telegraf.DeliveryInfo, maxTracked)
+	return a
+}
+
+// AddTrackingMetricGroup adds the metrics and reports the group as delivered
+// right away.
+func (a *Accumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	a.AddMetrics(group)
+	tracked, id := metric.WithGroupTracking(group, func(info telegraf.DeliveryInfo) {
+		a.delivered <- info
+	})
+	for _, m := range tracked {
+		m.Accept()
+	}
+	return id
+}
+
+func (a *Accumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
 func (a *Accumulator) SetPrecision(precision, interval time.Duration) {
 	return
 }